@@ -1,6 +1,7 @@
 package main
 
 import (
+	"embed"
 	"errors"
 	"fmt"
 	"github.com/kk88183080k/goWeb/msgo"
@@ -24,6 +25,11 @@ type user struct {
 	Email   string   `xml:"email"json:"email"`
 }
 
+// tplFS 编译进程序中的模板，配置 [fs] source="embed" 时使用
+//
+//go:embed tpl
+var tplFS embed.FS
+
 func main() {
 
 	engine := msgo.Default()
@@ -37,7 +43,7 @@ func main() {
 	goPoolTest(engine)
 
 	//engine.LoadTemplate("tpl/*.html")
-	engine.LoadTemplateByConf()
+	engine.LoadTemplateByConfFS(tplFS)
 	engine.Start("127.0.0.1", 8080)
 	//engine.StartByTLS("127.0.0.1:8888", "cert/server.pem", "cert/server.key")
 }
@@ -266,7 +272,7 @@ func reqResponseTest(engine *msgo.Engine) {
 		if err != nil {
 			log.Println("执行异常：", err)
 		}
-	})
+	}).Named("user.info.json.struct")
 	group.Get("/info/xml/struct", func(ctx *msgo.Context) {
 		user := user{}
 		user.Name = "李江卫"
@@ -289,7 +295,12 @@ func reqResponseTest(engine *msgo.Engine) {
 	})
 
 	group.Get("/redirect", func(ctx *msgo.Context) {
-		ctx.Redirect(http.StatusFound, "/user/info/json/struct")
+		url, err := engine.URLFor("user.info.json.struct")
+		if err != nil {
+			log.Println("执行异常：", err)
+			return
+		}
+		ctx.Redirect(http.StatusFound, url)
 	})
 	group.Get("/string", func(ctx *msgo.Context) {
 		ctx.String(http.StatusOK, "%s 您好", "李江卫")
@@ -324,7 +335,12 @@ func reqResponseTest(engine *msgo.Engine) {
 		}
 	})
 	group.Get("/redirectOptions", func(ctx *msgo.Context) {
-		ctx.RedirectOptions(http.StatusFound, "/user/info/json/struct")
+		url, err := engine.URLFor("user.info.json.struct")
+		if err != nil {
+			log.Println("执行异常：", err)
+			return
+		}
+		ctx.RedirectOptions(http.StatusFound, url)
 	})
 	group.Get("/htmlOptions", func(ctx *msgo.Context) {
 		ctx.HtmlOptions(http.StatusOK, "<h1>x386</h1>")
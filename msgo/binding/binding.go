@@ -9,3 +9,5 @@ type Binding interface {
 
 var JsonBind jsonBinding = jsonBinding{}
 var XmlBind xmlBinding = xmlBinding{}
+var QueryBind queryBinding = queryBinding{}
+var FormBind formBinding = formBinding{}
@@ -0,0 +1,50 @@
+package binding
+
+import (
+	"mime"
+	"net/http"
+)
+
+// defaultMaxMemory 解析 multipart 表单时文件保存在内存中的最大字节数，超过时保存到临时文件
+const defaultMaxMemory = 32 << 20
+
+// formBinding 将 application/x-www-form-urlencoded 或 multipart/form-data 请求体映射到结构体中，
+// 字段名取 form 标签，映射规则见 formMapper
+type formBinding struct {
+	MaxMemory int64 // 解析 multipart 表单时使用的最大内存，默认 32MB
+}
+
+func (f *formBinding) Name() string {
+	return "form"
+}
+
+func (f *formBinding) Bind(r *http.Request, v any) error {
+	if isMultipart(r) {
+		maxMemory := f.MaxMemory
+		if maxMemory <= 0 {
+			maxMemory = defaultMaxMemory
+		}
+		// 已解析过时不会重复解析
+		if err := r.ParseMultipartForm(maxMemory); err != nil {
+			return err
+		}
+	} else if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	var err error
+	if r.MultipartForm != nil {
+		err = mapFormFiles(v, r.PostForm, r.MultipartForm.File, "form")
+	} else {
+		err = mapForm(v, r.PostForm, "form")
+	}
+	if err != nil {
+		return err
+	}
+	return validate(v)
+}
+
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
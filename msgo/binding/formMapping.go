@@ -0,0 +1,451 @@
+package binding
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType     = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// formMapper 将 url.Values 形式的参数映射到结构体中
+//
+//	type Query struct {
+//		Page     int               `query:"page" default:"1"`
+//		Ids      []int64           `query:"ids"`                            // ids=1&ids=2
+//		Birthday time.Time         `query:"birthday" time_format:"2006-01-02"`
+//		User     map[string]string `query:"user"`                           // user[name]=tom&user[age]=18
+//		Filter   struct {
+//			Name string `query:"name"`                                        // filter[name]=tom
+//		} `query:"filter"`
+//	}
+//
+// 字段名依次取 tags 中的标签，都没有时使用字段名，标签为 - 时忽略该字段
+// 匿名嵌入的结构体展开为其字段，其他结构体字段按 name[field] 映射
+// 参数不存在时使用 default 标签的值，切片的默认值以 , 分隔
+// time.Time 按 time_format 标签解析，默认为 RFC3339，unix、unixmilli、unixnano 表示时间戳，
+// time_location 标签指定时区，默认为本地时区
+// *multipart.FileHeader、[]*multipart.FileHeader 字段映射上传的文件
+type formMapper struct {
+	values map[string][]string
+	files  map[string][]*multipart.FileHeader
+	tags   []string
+}
+
+// mapForm 按 tags 中的标签将 values 映射到 v 中，v 为结构体或 map[string]string、map[string][]string 的指针
+func mapForm(v any, values map[string][]string, tags ...string) error {
+	return mapFormFiles(v, values, nil, tags...)
+}
+
+// mapFormFiles 同 mapForm，同时将上传的文件 files 映射到文件字段中
+func mapFormFiles(v any, values map[string][]string, files map[string][]*multipart.FileHeader, tags ...string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("is not Pointer type")
+	}
+	m := &formMapper{values: values, files: files, tags: tags}
+
+	rv = rv.Elem()
+	switch rv.Kind() {
+	case reflect.Struct:
+		return m.mapStruct(rv, "")
+	case reflect.Map:
+		return m.mapValues(rv)
+	default:
+		return fmt.Errorf("unsupported type %s, must be struct or map", rv.Type())
+	}
+}
+
+func (m *formMapper) mapStruct(rv reflect.Value, prefix string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+		name, tagged, ok := m.fieldName(field)
+		if !ok {
+			continue
+		}
+
+		if field.Anonymous && !tagged {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := m.mapEmbedded(fv, prefix); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "[" + name + "]"
+		}
+		if err := m.mapField(field, fv, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapEmbedded 匿名嵌入的结构体展开为其字段，嵌入的是指针时只在有对应参数时创建
+func (m *formMapper) mapEmbedded(fv reflect.Value, prefix string) error {
+	if fv.Kind() != reflect.Pointer {
+		return m.mapStruct(fv, prefix)
+	}
+	if !fv.IsNil() {
+		return m.mapStruct(fv.Elem(), prefix)
+	}
+	if !fv.CanSet() {
+		return nil
+	}
+	elem := reflect.New(fv.Type().Elem())
+	if err := m.mapStruct(elem.Elem(), prefix); err != nil {
+		return err
+	}
+	if !elem.Elem().IsZero() {
+		fv.Set(elem)
+	}
+	return nil
+}
+
+// fieldName 按 tags 的顺序取字段的名称，tagged 表示是否由标签指定
+func (m *formMapper) fieldName(field reflect.StructField) (name string, tagged, ok bool) {
+	for _, tag := range m.tags {
+		val, exists := field.Tag.Lookup(tag)
+		if !exists {
+			continue
+		}
+		name = strings.Split(val, ",")[0]
+		if name == "-" {
+			return "", true, false
+		}
+		if name != "" {
+			return name, true, true
+		}
+	}
+	return field.Name, false, true
+}
+
+func (m *formMapper) mapField(field reflect.StructField, fv reflect.Value, key string) error {
+	switch fv.Type() {
+	case fileHeaderType:
+		if files := m.files[key]; len(files) > 0 {
+			fv.Set(reflect.ValueOf(files[0]))
+		}
+		return nil
+	case fileHeadersType:
+		if files := m.files[key]; len(files) > 0 {
+			fv.Set(reflect.ValueOf(files))
+		}
+		return nil
+	}
+
+	if isScalar(fv.Type()) {
+		vals, ok := m.lookup(key, field)
+		if !ok {
+			return nil
+		}
+		return setScalar(fv, vals[0], field)
+	}
+
+	switch fv.Kind() {
+	case reflect.Pointer:
+		if !m.exists(key, field, fv.Type().Elem()) {
+			return nil
+		}
+		elem := reflect.New(fv.Type().Elem())
+		if err := m.mapField(field, elem.Elem(), key); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case reflect.Struct:
+		return m.mapStruct(fv, key)
+	case reflect.Map:
+		return m.mapMap(field, fv, key)
+	case reflect.Slice:
+		vals, ok := m.lookup(key, field)
+		if !ok {
+			return nil
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setScalar(slice.Index(i), val, field); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	case reflect.Array:
+		vals, ok := m.lookup(key, field)
+		if !ok {
+			return nil
+		}
+		if len(vals) > fv.Len() {
+			return fmt.Errorf("%s: too many values, the array length is %d", key, fv.Len())
+		}
+		for i, val := range vals {
+			if err := setScalar(fv.Index(i), val, field); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported type %s", key, fv.Type())
+	}
+}
+
+// mapMap 将 key[name]=value 形式的参数映射到 map 中
+func (m *formMapper) mapMap(field reflect.StructField, fv reflect.Value, key string) error {
+	if fv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("%s: unsupported map key type %s", key, fv.Type().Key())
+	}
+	elemType := fv.Type().Elem()
+	for k, vals := range m.values {
+		name, ok := mapKey(k, key)
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		elem := reflect.New(elemType).Elem()
+		if elemType.Kind() == reflect.Slice && !isScalar(elemType) {
+			elem = reflect.MakeSlice(elemType, len(vals), len(vals))
+			for i, val := range vals {
+				if err := setScalar(elem.Index(i), val, field); err != nil {
+					return err
+				}
+			}
+		} else if err := setScalar(elem, vals[0], field); err != nil {
+			return err
+		}
+		if fv.IsNil() {
+			fv.Set(reflect.MakeMap(fv.Type()))
+		}
+		fv.SetMapIndex(reflect.ValueOf(name).Convert(fv.Type().Key()), elem)
+	}
+	return nil
+}
+
+// mapValues 将全部参数映射到 map[string]string 或 map[string][]string 中
+func (m *formMapper) mapValues(rv reflect.Value) error {
+	switch v := rv.Addr().Interface().(type) {
+	case *map[string]string:
+		if *v == nil {
+			*v = make(map[string]string, len(m.values))
+		}
+		for key, vals := range m.values {
+			if len(vals) > 0 {
+				(*v)[key] = vals[0]
+			}
+		}
+	case *map[string][]string:
+		if *v == nil {
+			*v = make(map[string][]string, len(m.values))
+		}
+		for key, vals := range m.values {
+			(*v)[key] = vals
+		}
+	default:
+		return fmt.Errorf("unsupported type %s, must be map[string]string or map[string][]string", rv.Type())
+	}
+	return nil
+}
+
+// lookup 返回 key 对应的参数，不存在时返回 default 标签的值
+func (m *formMapper) lookup(key string, field reflect.StructField) ([]string, bool) {
+	if vals, ok := m.values[key]; ok && len(vals) > 0 {
+		return vals, true
+	}
+	def, ok := field.Tag.Lookup("default")
+	if !ok {
+		return nil, false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !isScalar(t) {
+		return strings.Split(def, ","), true
+	}
+	return []string{def}, true
+}
+
+// exists 指针字段是否有对应的参数或默认值
+func (m *formMapper) exists(key string, field reflect.StructField, t reflect.Type) bool {
+	if _, ok := m.lookup(key, field); ok {
+		return true
+	}
+	if isScalar(t) {
+		return false
+	}
+	for k := range m.values {
+		if strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+	for k := range m.files {
+		if strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// mapKey 参数名为 prefix[name] 时返回 name
+func mapKey(key, prefix string) (string, bool) {
+	if len(key) <= len(prefix)+2 || !strings.HasPrefix(key, prefix) || key[len(prefix)] != '[' || key[len(key)-1] != ']' {
+		return "", false
+	}
+	name := key[len(prefix)+1 : len(key)-1]
+	if strings.ContainsAny(name, "[]") {
+		return "", false
+	}
+	return name, true
+}
+
+// isScalar 是否为单个参数值映射的类型
+func isScalar(t reflect.Type) bool {
+	if t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	}
+	return true
+}
+
+func setScalar(v reflect.Value, val string, field reflect.StructField) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setScalar(elem.Elem(), val, field); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		t, err := parseTime(val, field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		if val == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(val)); err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+		return nil
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		var b bool
+		switch val {
+		case "":
+		case "on":
+			b = true
+		default:
+			b, err = strconv.ParseBool(val)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if val != "" {
+			i, err = strconv.ParseInt(val, 10, v.Type().Bits())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		if val != "" {
+			u, err = strconv.ParseUint(val, 10, v.Type().Bits())
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if val != "" {
+			f, err = strconv.ParseFloat(val, v.Type().Bits())
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("%s: unsupported type %s", field.Name, v.Type())
+		}
+		v.Set(reflect.ValueOf(val))
+	default:
+		return fmt.Errorf("%s: unsupported type %s", field.Name, v.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", field.Name, err)
+	}
+	return nil
+}
+
+// parseTime 按 time_format、time_location 标签解析时间，值为空时返回零值
+func parseTime(val string, field reflect.StructField) (time.Time, error) {
+	if val == "" {
+		return time.Time{}, nil
+	}
+
+	layout := field.Tag.Get("time_format")
+	switch layout {
+	case "unix", "unixmilli", "unixnano":
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch layout {
+		case "unix":
+			return time.Unix(n, 0), nil
+		case "unixmilli":
+			return time.UnixMilli(n), nil
+		default:
+			return time.Unix(0, n), nil
+		}
+	case "":
+		layout = time.RFC3339
+	}
+
+	loc := time.Local
+	if name := field.Tag.Get("time_location"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = l
+	}
+	return time.ParseInLocation(layout, val, loc)
+}
@@ -0,0 +1,214 @@
+package binding
+
+import (
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mapAddress struct {
+	City string `form:"city"`
+}
+
+type mapBase struct {
+	Id int64 `form:"id"`
+}
+
+type mapUser struct {
+	mapBase
+	Name     string            `form:"name"`
+	Age      *int              `form:"age"`
+	Page     int               `form:"page" default:"1"`
+	Tags     []string          `form:"tags" default:"a,b"`
+	Scores   [2]int            `form:"scores"`
+	Vip      bool              `form:"vip"`
+	Timeout  time.Duration     `form:"timeout"`
+	Birthday time.Time         `form:"birthday" time_format:"2006-01-02" time_location:"UTC"`
+	Login    time.Time         `form:"login" time_format:"unix"`
+	Extra    map[string]string `form:"extra"`
+	Address  mapAddress        `form:"address"`
+	Ignored  string            `form:"-"`
+}
+
+func TestMapForm(t *testing.T) {
+	age := 18
+	tests := []struct {
+		name   string
+		values map[string][]string
+		want   mapUser
+	}{
+		{
+			name:   "default",
+			values: map[string][]string{},
+			want:   mapUser{Page: 1, Tags: []string{"a", "b"}},
+		},
+		{
+			name: "all",
+			values: map[string][]string{
+				"id":            {"7"},
+				"name":          {"tom"},
+				"age":           {"18"},
+				"page":          {"3"},
+				"tags":          {"x", "y", "z"},
+				"scores":        {"90", "80"},
+				"vip":           {"on"},
+				"timeout":       {"1s"},
+				"birthday":      {"2000-01-02"},
+				"login":         {"1700000000"},
+				"extra[from]":   {"ad"},
+				"address[city]": {"bj"},
+				"Ignored":       {"x"},
+				"-":             {"x"},
+			},
+			want: mapUser{
+				mapBase:  mapBase{Id: 7},
+				Name:     "tom",
+				Age:      &age,
+				Page:     3,
+				Tags:     []string{"x", "y", "z"},
+				Scores:   [2]int{90, 80},
+				Vip:      true,
+				Timeout:  time.Second,
+				Birthday: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+				Login:    time.Unix(1700000000, 0),
+				Extra:    map[string]string{"from": "ad"},
+				Address:  mapAddress{City: "bj"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		var got mapUser
+		if err := mapForm(&got, tt.values, "form"); err != nil {
+			t.Errorf("%s: mapForm error = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: mapForm = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMapForm_Map(t *testing.T) {
+	values := map[string][]string{"a": {"1", "2"}, "b": {"3"}}
+	var single map[string]string
+	if err := mapForm(&single, values); err != nil || !reflect.DeepEqual(single, map[string]string{"a": "1", "b": "3"}) {
+		t.Errorf("map[string]string = %v, %v", single, err)
+	}
+	var multi map[string][]string
+	if err := mapForm(&multi, values); err != nil || !reflect.DeepEqual(multi, values) {
+		t.Errorf("map[string][]string = %v, %v", multi, err)
+	}
+}
+
+func TestMapForm_Error(t *testing.T) {
+	tests := []struct {
+		name   string
+		obj    any
+		values map[string][]string
+		want   string
+	}{
+		{"not pointer", mapUser{}, nil, "is not Pointer type"},
+		{"unsupported type", new(int), nil, "unsupported type int"},
+		{"unsupported map", new(map[string]int), nil, "unsupported type map[string]int"},
+		{"bad int", new(struct {
+			Page int `form:"page"`
+		}), map[string][]string{"page": {"x"}}, "Page: strconv.ParseInt"},
+		{"bad default", new(struct {
+			Page int `form:"page" default:"x"`
+		}), nil, "Page: strconv.ParseInt"},
+		{"int overflow", new(struct {
+			Level int8 `form:"level"`
+		}), map[string][]string{"level": {"300"}}, "value out of range"},
+		{"bad bool", new(struct {
+			Vip bool `form:"vip"`
+		}), map[string][]string{"vip": {"yes"}}, "Vip: strconv.ParseBool"},
+		{"bad slice item", new(struct {
+			Ids []int `form:"ids"`
+		}), map[string][]string{"ids": {"1", "x"}}, "Ids: strconv.ParseInt"},
+		{"unsupported map key", new(struct {
+			Extra map[int]string `form:"extra"`
+		}), map[string][]string{"extra[1]": {"a"}}, "extra: unsupported map key type int"},
+		{"array overflow", new(struct {
+			Scores [2]int `form:"scores"`
+		}), map[string][]string{"scores": {"1", "2", "3"}}, "scores: too many values, the array length is 2"},
+		{"bad time", new(struct {
+			Birthday time.Time `form:"birthday" time_format:"2006-01-02"`
+		}), map[string][]string{"birthday": {"01/02/2000"}}, "Birthday: parsing time"},
+		{"bad time_location", new(struct {
+			Birthday time.Time `form:"birthday" time_format:"2006-01-02" time_location:"Mars/Base"`
+		}), map[string][]string{"birthday": {"2000-01-02"}}, "Birthday: unknown time zone Mars/Base"},
+		{"bad unix time", new(struct {
+			Login time.Time `form:"login" time_format:"unix"`
+		}), map[string][]string{"login": {"now"}}, "Login: strconv.ParseInt"},
+		{"bad duration", new(struct {
+			Timeout time.Duration `form:"timeout"`
+		}), map[string][]string{"timeout": {"10"}}, "Timeout: time: missing unit"},
+		{"unsupported field", new(struct {
+			Fn func() `form:"fn"`
+		}), map[string][]string{"fn": {"x"}}, "Fn: unsupported type func()"},
+	}
+	for _, tt := range tests {
+		err := mapForm(tt.obj, tt.values, "form")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: mapForm error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestMapFormFiles(t *testing.T) {
+	avatar := &multipart.FileHeader{Filename: "avatar.png"}
+	photo1 := &multipart.FileHeader{Filename: "1.png"}
+	photo2 := &multipart.FileHeader{Filename: "2.png"}
+	type profile struct {
+		Cover *multipart.FileHeader `form:"cover"`
+	}
+	type upload struct {
+		Title   string                  `form:"title"`
+		Avatar  *multipart.FileHeader   `form:"avatar"`
+		Photos  []*multipart.FileHeader `form:"photos"`
+		Missing *multipart.FileHeader   `form:"missing"`
+		Profile *profile                `form:"profile"`
+	}
+
+	tests := []struct {
+		name   string
+		values map[string][]string
+		files  map[string][]*multipart.FileHeader
+		want   upload
+	}{
+		{
+			name: "no files",
+			values: map[string][]string{
+				"title": {"hello"},
+			},
+			want: upload{Title: "hello"},
+		},
+		{
+			name:   "files",
+			values: map[string][]string{"title": {"hello"}},
+			files: map[string][]*multipart.FileHeader{
+				"avatar":         {avatar, photo1},
+				"photos":         {photo1, photo2},
+				"profile[cover]": {avatar},
+			},
+			want: upload{
+				Title:   "hello",
+				Avatar:  avatar,
+				Photos:  []*multipart.FileHeader{photo1, photo2},
+				Profile: &profile{Cover: avatar},
+			},
+		},
+	}
+	for _, tt := range tests {
+		var got upload
+		if err := mapFormFiles(&got, tt.values, tt.files, "form"); err != nil {
+			t.Errorf("%s: mapFormFiles error = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: mapFormFiles = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package binding
+
+import (
+	"net/http"
+)
+
+// queryBinding 将地址中的查询参数映射到结构体中，字段名依次取 query、form 标签，映射规则见 formMapper
+type queryBinding struct {
+}
+
+func (q *queryBinding) Name() string {
+	return "query"
+}
+
+func (q *queryBinding) Bind(r *http.Request, v any) error {
+	if err := mapForm(v, r.URL.Query(), "query", "form"); err != nil {
+		return err
+	}
+	return validate(v)
+}
@@ -40,7 +40,7 @@ func (d *defaultStructValidator) ValidateExtStruct(v any) error {
 		}
 		return validateRet
 	case reflect.Struct:
-		d.validateStruct(v)
+		return d.validateStruct(v)
 	}
 	return nil
 }
@@ -0,0 +1,131 @@
+package msgo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// paramConstraint 路径参数的约束
+//
+//	:id<int>          整数
+//	:id<uint>         非负整数
+//	:price<float>     浮点数
+//	:ok<bool>         布尔值
+//	:slug<[a-z-]+>    正则表达式
+//	{id:[0-9]+}       正则表达式
+type paramConstraint struct {
+	expr  string          // 约束表达式
+	regex *regexp.Regexp  // 正则约束
+	typed *typeConstraint // 类型约束
+}
+
+// typeConstraint 类型约束，match 用于路由匹配，parse 用于获取解析后的值
+type typeConstraint struct {
+	match func(val string) bool
+	parse func(val string) any
+}
+
+// typeConstraints 内置的类型约束
+var typeConstraints = map[string]*typeConstraint{
+	"int": {
+		match: func(val string) bool {
+			_, err := strconv.ParseInt(val, 10, 64)
+			return err == nil
+		},
+		parse: func(val string) any {
+			v, _ := strconv.ParseInt(val, 10, 64)
+			return v
+		},
+	},
+	"uint": {
+		match: func(val string) bool {
+			_, err := strconv.ParseUint(val, 10, 64)
+			return err == nil
+		},
+		parse: func(val string) any {
+			v, _ := strconv.ParseUint(val, 10, 64)
+			return v
+		},
+	},
+	"float": {
+		match: func(val string) bool {
+			_, err := strconv.ParseFloat(val, 64)
+			return err == nil
+		},
+		parse: func(val string) any {
+			v, _ := strconv.ParseFloat(val, 64)
+			return v
+		},
+	},
+	"bool": {
+		match: func(val string) bool {
+			_, err := strconv.ParseBool(val)
+			return err == nil
+		},
+		parse: func(val string) any {
+			v, _ := strconv.ParseBool(val)
+			return v
+		},
+	},
+}
+
+func newParamConstraint(expr string) *paramConstraint {
+	if typed, ok := typeConstraints[expr]; ok {
+		return &paramConstraint{expr: expr, typed: typed}
+	}
+	regex, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic(fmt.Sprintf("路径参数的约束 %s 不是合法的正则表达式: %v", expr, err))
+	}
+	return &paramConstraint{expr: expr, regex: regex}
+}
+
+// match 参数值是否满足约束
+func (c *paramConstraint) match(val string) bool {
+	if c.typed != nil {
+		return c.typed.match(val)
+	}
+	return c.regex.MatchString(val)
+}
+
+// value 返回参数值按约束解析后的值，正则约束返回字符串
+func (c *paramConstraint) value(val string) any {
+	if c.typed != nil {
+		return c.typed.parse(val)
+	}
+	return val
+}
+
+// parseParamSegment 解析参数路径段，返回参数名及约束表达式
+//
+//	:id        -> id, ""
+//	:id<int>   -> id, int
+//	{id}       -> id, ""
+//	{id:[0-9]+} -> id, [0-9]+
+//	*          -> "", ""
+func parseParamSegment(seg string) (name, expr string) {
+	switch seg[0] {
+	case '{':
+		if seg[len(seg)-1] != '}' {
+			panic("路径参数 " + seg + " 缺少 }")
+		}
+		seg = seg[1 : len(seg)-1]
+		if i := strings.IndexByte(seg, ':'); i >= 0 {
+			return seg[:i], seg[i+1:]
+		}
+		return seg, ""
+	case ':':
+		seg = seg[1:]
+		if i := strings.IndexByte(seg, '<'); i >= 0 {
+			if seg[len(seg)-1] != '>' {
+				panic("路径参数 :" + seg + " 缺少 >")
+			}
+			return seg[:i], seg[i+1 : len(seg)-1]
+		}
+		return seg, ""
+	default:
+		return "", ""
+	}
+}
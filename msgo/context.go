@@ -1,6 +1,7 @@
 package msgo
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -12,11 +13,13 @@ import (
 	"html/template"
 	"io"
 	"log"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,13 +27,22 @@ type Context struct {
 	W                     http.ResponseWriter
 	R                     *http.Request
 	e                     *Engine
-	queryCache            url.Values   // get请求，地址中的参数
-	formCache             url.Values   // post请求，body中的参数
-	DisallowUnknownFields bool         // 客户端传的参数中有，但后台结构体中没有就报错
-	IsValidate            bool         // 客户端传的参数是否校验
-	StatusCode            int          // 返回的状态码
-	Logger                *logs.Logger // 日志组件
-}
+	queryCache            url.Values     // get请求，地址中的参数
+	formCache             url.Values     // post请求，body中的参数
+	DisallowUnknownFields bool           // 客户端传的参数中有，但后台结构体中没有就报错
+	IsValidate            bool           // 客户端传的参数是否校验
+	StatusCode            int            // Render 时传入的状态码，实际写入的状态码使用 Status()
+	Logger                *logs.Logger   // 日志组件
+	params                Params         // 路由中捕获的路径参数
+	handlers              []Handler      // 当前请求的处理函数链
+	index                 int            // 正在执行的处理函数在 handlers 中的位置
+	keys                  map[string]any // 请求内中间件、处理函数间传递的数据
+	keysMu                sync.RWMutex   // 保护 keys
+	writer                responseWriter // W 的默认值，记录状态码及响应体大小
+}
+
+// abortIndex 调用 Abort 后 index 的值，大于任何处理函数链的长度
+const abortIndex = math.MaxInt16
 
 // 解析表单使用的最大内存参数
 const defaultMultipartMemory = 2 << 16
@@ -78,7 +90,7 @@ func (c *Context) HtmlTemplateGlobNoLoad(name string, funcMap template.FuncMap,
 func (c *Context) HtmlTemplate(status int, name string, data any) error {
 	c.W.Header().Add("Content-type", "text/html; charset=utf-8")
 	c.W.WriteHeader(status)
-	return c.e.render.Template.ExecuteTemplate(c.W, name, data)
+	return c.e.template().ExecuteTemplate(c.W, name, data)
 }
 
 func (c *Context) JSON(staus int, data any) error {
@@ -155,8 +167,12 @@ func (c *Context) Fail(status int, format string) error {
 
 // Render 公共的解析方法
 func (c *Context) Render(statusCode int, w http.ResponseWriter, viewResv render.Render) error {
-	// 视图解析器中，设置content-type, 返回数据
 	c.StatusCode = statusCode
+	// 重定向由 http.Redirect 写入状态码
+	if _, ok := viewResv.(*render.Redirect); !ok {
+		w.WriteHeader(statusCode)
+	}
+	// 视图解析器中，设置content-type, 返回数据
 	return viewResv.Render(w)
 }
 
@@ -177,17 +193,300 @@ func (c *Context) RedirectOptions(status int, url string) error {
 }
 
 func (c *Context) HtmlOptions(status int, data string) error {
-	c.W.WriteHeader(status)
-	return c.Render(status, c.W, &render.HtmlOptionsRender{Name: "", Data: data, Template: c.e.render.Template, IsTemplate: false})
+	return c.Render(status, c.W, &render.HtmlOptionsRender{Name: "", Data: data, Template: c.e.template(), IsTemplate: false})
 }
 
 func (c *Context) HtmlTemplateOptions(status int, name string, data any) error {
-	c.W.WriteHeader(status)
-	return c.Render(status, c.W, &render.HtmlOptionsRender{Name: name, Data: data, Template: c.e.render.Template, IsTemplate: true})
+	return c.Render(status, c.W, &render.HtmlOptionsRender{Name: name, Data: data, Template: c.e.template(), IsTemplate: true})
 }
 
 /*****接口抽象写法** end ***/
 
+/*****响应状态** start ***/
+
+// Status 响应的状态码，没有调用 WriteHeader 时为 200
+func (c *Context) Status() int {
+	return c.writer.Status()
+}
+
+// Written 响应头是否已写入
+func (c *Context) Written() bool {
+	return c.writer.Written()
+}
+
+// Size 已写入的响应体字节数，响应头还没有写入时为 -1
+func (c *Context) Size() int {
+	return c.writer.Size()
+}
+
+/*****响应状态** end ***/
+
+/*****路径参数** start ***/
+
+// Param 获取路由中捕获的路径参数，如 /user/get/:id 中的 id
+func (c *Context) Param(key string) string {
+	return c.params.ByName(key)
+}
+
+// ParamValue 获取路径参数按约束解析后的值，如 /user/get/:id<int> 中的 id 为 int64
+// 参数不存在时返回 nil
+func (c *Context) ParamValue(key string) any {
+	for _, p := range c.params {
+		if p.Key == key {
+			return p.TypedValue()
+		}
+	}
+	return nil
+}
+
+// ParamInt 获取 <int> 约束的路径参数
+func (c *Context) ParamInt(key string) (int64, bool) {
+	val, ok := c.ParamValue(key).(int64)
+	return val, ok
+}
+
+// Params 获取路由中捕获的全部路径参数
+func (c *Context) Params() Params {
+	return c.params
+}
+
+/*****路径参数** end ***/
+
+/*****中间件链** start ***/
+
+// run 从头执行处理函数链
+func (c *Context) run(handlers []Handler) {
+	c.handlers = handlers
+	c.index = -1
+	c.Next()
+}
+
+// Next 在中间件中执行处理函数链中后续的中间件及业务处理函数，返回后可继续处理响应
+func (c *Context) Next() {
+	c.index++
+	if c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+	}
+}
+
+// Abort 中止处理函数链，之后调用 Next 不再执行后续的处理函数，已执行的中间件中 Next 之后的代码仍会执行
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// AbortWithStatus 中止处理函数链并返回状态码
+func (c *Context) AbortWithStatus(status int) {
+	c.Abort()
+	c.W.WriteHeader(status)
+}
+
+// AbortWithStatusJSON 中止处理函数链并以 json 返回 data
+//
+//	ctx.AbortWithStatusJSON(http.StatusUnauthorized, msgo.DefaultR().Fail(401, "未登录").Response())
+func (c *Context) AbortWithStatusJSON(status int, data any) error {
+	c.Abort()
+	return c.JSON(status, data)
+}
+
+// IsAborted 处理函数链是否已中止
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+/*****中间件链** end ***/
+
+/*****请求内的数据** start ***/
+
+// Set 保存请求内的数据，用于中间件向后续的处理函数传递数据，如登录用户、请求 ID
+func (c *Context) Set(key string, value any) {
+	c.keysMu.Lock()
+	defer c.keysMu.Unlock()
+	if c.keys == nil {
+		c.keys = make(map[string]any)
+	}
+	c.keys[key] = value
+}
+
+// Get 获取请求内的数据
+func (c *Context) Get(key string) (value any, exists bool) {
+	c.keysMu.RLock()
+	defer c.keysMu.RUnlock()
+	value, exists = c.keys[key]
+	return
+}
+
+// MustGet 获取请求内的数据，不存在时 panic
+func (c *Context) MustGet(key string) any {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("Key \"" + key + "\" does not exist")
+}
+
+// GetString 获取请求内的字符串，不存在或类型不符时返回零值，其他 GetXxx 相同
+func (c *Context) GetString(key string) (s string) {
+	if val, ok := c.Get(key); ok && val != nil {
+		s, _ = val.(string)
+	}
+	return
+}
+
+func (c *Context) GetBool(key string) (b bool) {
+	if val, ok := c.Get(key); ok && val != nil {
+		b, _ = val.(bool)
+	}
+	return
+}
+
+func (c *Context) GetInt(key string) (i int) {
+	if val, ok := c.Get(key); ok && val != nil {
+		i, _ = val.(int)
+	}
+	return
+}
+
+func (c *Context) GetInt64(key string) (i int64) {
+	if val, ok := c.Get(key); ok && val != nil {
+		i, _ = val.(int64)
+	}
+	return
+}
+
+func (c *Context) GetUint(key string) (i uint) {
+	if val, ok := c.Get(key); ok && val != nil {
+		i, _ = val.(uint)
+	}
+	return
+}
+
+func (c *Context) GetUint64(key string) (i uint64) {
+	if val, ok := c.Get(key); ok && val != nil {
+		i, _ = val.(uint64)
+	}
+	return
+}
+
+func (c *Context) GetFloat64(key string) (f float64) {
+	if val, ok := c.Get(key); ok && val != nil {
+		f, _ = val.(float64)
+	}
+	return
+}
+
+func (c *Context) GetTime(key string) (t time.Time) {
+	if val, ok := c.Get(key); ok && val != nil {
+		t, _ = val.(time.Time)
+	}
+	return
+}
+
+func (c *Context) GetDuration(key string) (d time.Duration) {
+	if val, ok := c.Get(key); ok && val != nil {
+		d, _ = val.(time.Duration)
+	}
+	return
+}
+
+func (c *Context) GetStringSlice(key string) (ss []string) {
+	if val, ok := c.Get(key); ok && val != nil {
+		ss, _ = val.([]string)
+	}
+	return
+}
+
+func (c *Context) GetStringMap(key string) (sm map[string]any) {
+	if val, ok := c.Get(key); ok && val != nil {
+		sm, _ = val.(map[string]any)
+	}
+	return
+}
+
+func (c *Context) GetStringMapString(key string) (sms map[string]string) {
+	if val, ok := c.Get(key); ok && val != nil {
+		sms, _ = val.(map[string]string)
+	}
+	return
+}
+
+/*****请求内的数据** end ***/
+
+/*****context.Context** start ***/
+
+// Context 实现了 context.Context，可直接传给数据库、rpc 等需要 context.Context 的调用，
+// 客户端断开连接或请求结束时 Done 关闭
+// 请求结束后 Context 会被复用，不能在请求结束后继续使用，异步任务中使用 ctx.Copy()
+var _ context.Context = (*Context)(nil)
+
+// Deadline 同 ctx.R.Context().Deadline()
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.R == nil {
+		return
+	}
+	return c.R.Context().Deadline()
+}
+
+// Done 同 ctx.R.Context().Done()
+func (c *Context) Done() <-chan struct{} {
+	if c.R == nil {
+		return nil
+	}
+	return c.R.Context().Done()
+}
+
+// Err 同 ctx.R.Context().Err()
+func (c *Context) Err() error {
+	if c.R == nil {
+		return nil
+	}
+	return c.R.Context().Err()
+}
+
+// Value key 为 string 时先从 Set 保存的数据中获取，不存在时从 ctx.R.Context() 中获取
+func (c *Context) Value(key any) any {
+	if k, ok := key.(string); ok {
+		if val, exists := c.Get(k); exists {
+			return val
+		}
+	}
+	if c.R == nil {
+		return nil
+	}
+	return c.R.Context().Value(key)
+}
+
+// Copy 返回当前 Context 的副本，用于请求结束后仍在执行的异步任务(如提交到 mspool 的任务)
+// 副本不会放回池中，复制了请求、路径参数、Set 保存的数据，Done 同 ctx.R.Context().Done()
+// 副本不能写入响应，也不能调用 Next
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		R:                     c.R,
+		e:                     c.e,
+		queryCache:            c.queryCache,
+		formCache:             c.formCache,
+		DisallowUnknownFields: c.DisallowUnknownFields,
+		IsValidate:            c.IsValidate,
+		StatusCode:            c.StatusCode,
+		Logger:                c.Logger,
+		params:                append(Params(nil), c.params...),
+		index:                 abortIndex,
+		writer:                c.writer,
+	}
+	cp.writer.ResponseWriter = nil
+	cp.W = &cp.writer
+
+	c.keysMu.RLock()
+	defer c.keysMu.RUnlock()
+	if c.keys != nil {
+		cp.keys = make(map[string]any, len(c.keys))
+		for k, v := range c.keys {
+			cp.keys[k] = v
+		}
+	}
+	return cp
+}
+
+/*****context.Context** end ***/
+
 /*****get 方式获取请求参数** start ***/
 func (c *Context) initQueryCache() {
 	if c.queryCache == nil {
@@ -352,6 +651,7 @@ func (c *Context) UploadFile(fileKey, dir string) (string, error) {
 
 /*****post json方式获取请求参数** start ***/
 
+// BindJson 将 json 请求体映射到结构体中，并按 validate 标签校验，出错时返回 400 状态码
 func (c *Context) BindJson(obj any) error {
 	jsonBinding := binding.JsonBind
 	jsonBinding.DisallowUnknownFields = c.DisallowUnknownFields
@@ -359,10 +659,44 @@ func (c *Context) BindJson(obj any) error {
 	return c.MustBindWith(obj, &jsonBinding)
 }
 
+// BindXml 将 xml 请求体映射到结构体中，并按 validate 标签校验，出错时返回 400 状态码
 func (c *Context) BindXml(obj any) error {
 	return c.MustBindWith(obj, &binding.XmlBind)
 }
 
+// BindQuery 将地址中的查询参数映射到结构体中并校验，字段名取 query、form 标签，规则见 binding.QueryBind
+//
+//	type UserQuery struct {
+//		Name string    `query:"name" validate:"required"`
+//		Page int       `query:"page" default:"1"`
+//		From time.Time `query:"from" time_format:"2006-01-02"`
+//	}
+func (c *Context) BindQuery(obj any) error {
+	return c.MustBindWith(obj, &binding.QueryBind)
+}
+
+// ShouldBindQuery 同 BindQuery，出错时不返回 400 状态码
+func (c *Context) ShouldBindQuery(obj any) error {
+	return c.ShouldBindWith(obj, &binding.QueryBind)
+}
+
+// BindForm 将 application/x-www-form-urlencoded 或 multipart/form-data 请求体映射到结构体中并校验，
+// 字段名取 form 标签，*multipart.FileHeader、[]*multipart.FileHeader 字段映射上传的文件
+//
+//	type Upload struct {
+//		Title  string                  `form:"title" validate:"required"`
+//		Avatar *multipart.FileHeader   `form:"avatar" validate:"required"`
+//		Photos []*multipart.FileHeader `form:"photos"`
+//	}
+func (c *Context) BindForm(obj any) error {
+	return c.MustBindWith(obj, &binding.FormBind)
+}
+
+// ShouldBindForm 同 BindForm，出错时不返回 400 状态码
+func (c *Context) ShouldBindForm(obj any) error {
+	return c.ShouldBindWith(obj, &binding.FormBind)
+}
+
 func (c *Context) MustBindWith(obj any, b binding.Binding) error {
 	//如果发生错误，返回400状态码 参数错误
 	if err := c.ShouldBindWith(obj, b); err != nil {
@@ -382,12 +716,12 @@ func (c *Context) ShouldBindWith(obj any, b binding.Binding) error {
 /*****错误处理** start ***/
 
 func (c *Context) ErrorHandler(err error) {
-	c.JsonOptions(c.e.errHandler(err))
+	c.JsonOptions(c.e.errorHandler()(err))
 }
 
 func (c *Context) HandlerWithError(code int, msg string, err error) {
 	if err != nil {
-		c.JsonOptions(c.e.errHandler(err))
+		c.JsonOptions(c.e.errorHandler()(err))
 		return
 	}
 
@@ -0,0 +1,371 @@
+package msgo
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestContext_Next_Abort(t *testing.T) {
+	engine := New()
+	var trace []string
+	mark := func(name string) MiddlewareFun {
+		return func(next Handler) Handler {
+			return func(ctx *Context) {
+				trace = append(trace, name+">")
+				next(ctx)
+				trace = append(trace, "<"+name)
+			}
+		}
+	}
+	auth := HandlerMiddleware(func(ctx *Context) {
+		if ctx.R.Header.Get("Authorization") == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"msg": "unauthorized"})
+			return
+		}
+		ctx.Next()
+		trace = append(trace, "aborted="+fmt.Sprint(ctx.IsAborted()))
+	})
+	api := engine.Group("/api")
+	api.Use(mark("log"))
+	user := api.Group("/user")
+	user.Use(auth)
+	user.Get("/info", func(ctx *Context) {
+		trace = append(trace, "handler")
+		ctx.String(http.StatusOK, "ok")
+	})
+	// 不调用 next 的中间件中止后续的处理函数
+	user.Get("/blocked", func(ctx *Context) {
+		trace = append(trace, "handler")
+	}, func(next Handler) Handler {
+		return func(ctx *Context) {
+			ctx.String(http.StatusForbidden, "blocked")
+		}
+	})
+
+	trace = nil
+	w := performRequest(engine, http.MethodGet, "/api/user/info")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "unauthorized") {
+		t.Errorf("abort: code = %d, body = %q", w.Code, w.Body.String())
+	}
+	if got := strings.Join(trace, " "); got != "log> <log" {
+		t.Errorf("abort trace = %q", got)
+	}
+
+	trace = nil
+	req := httptest.NewRequest(http.MethodGet, "/api/user/info", nil)
+	req.Header.Set("Authorization", "token")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Body.String() != "ok" {
+		t.Errorf("next: body = %q", w.Body.String())
+	}
+	if got := strings.Join(trace, " "); got != "log> handler aborted=false <log" {
+		t.Errorf("next trace = %q", got)
+	}
+
+	trace = nil
+	req = httptest.NewRequest(http.MethodGet, "/api/user/blocked", nil)
+	req.Header.Set("Authorization", "token")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || strings.Contains(strings.Join(trace, " "), "handler") {
+		t.Errorf("blocked: code = %d, trace = %q", w.Code, trace)
+	}
+}
+
+func TestContext_Set_Get(t *testing.T) {
+	engine := New()
+	now := time.Now()
+	engine.Use(func(next Handler) Handler {
+		return func(ctx *Context) {
+			if _, exists := ctx.Get("user"); exists {
+				t.Errorf("keys of the previous request were not reset")
+			}
+			ctx.Set("user", "tom")
+			ctx.Set("id", int64(10))
+			ctx.Set("login", now)
+			next(ctx)
+		}
+	})
+	engine.Group("/user").Get("/info", func(ctx *Context) {
+		// 并发读取
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = ctx.GetString("user")
+			}()
+		}
+		wg.Wait()
+		ctx.String(http.StatusOK, "%s %d %v %d %v", ctx.MustGet("user"), ctx.GetInt64("id"),
+			ctx.GetTime("login").Equal(now), ctx.GetInt("id"), ctx.GetBool("missing"))
+	})
+
+	for i := 0; i < 2; i++ {
+		if w := performRequest(engine, http.MethodGet, "/user/info"); w.Body.String() != "tom 10 true 0 false" {
+			t.Errorf("body = %q", w.Body.String())
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustGet of a missing key should panic")
+		}
+	}()
+	(&Context{}).MustGet("missing")
+}
+
+func TestContext_Context(t *testing.T) {
+	engine := New()
+	engine.Group("/user").Get("/info", func(ctx *Context) {
+		ctx.Set("user", "tom")
+		var c context.Context = ctx
+		<-c.Done()
+		ctx.String(http.StatusOK, "%v %v %v", c.Err(), c.Value("user"), c.Value(ctxKey("request")))
+	})
+
+	reqCtx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey("request"), "r1"))
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/user/info", nil).WithContext(reqCtx)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Body.String() != "context canceled tom r1" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
+
+func TestContext_Copy(t *testing.T) {
+	engine := New()
+	copied := make(chan *Context, 1)
+	engine.Group("/user").Get("/info/:id", func(ctx *Context) {
+		ctx.Set("user", ctx.GetQuery("name"))
+		if ctx.Param("id") == "1" {
+			copied <- ctx.Copy()
+		}
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	performRequest(engine, http.MethodGet, "/user/info/1?name=tom")
+	cp := <-copied
+	// 池中的 Context 被下一个请求复用后，副本中仍是原请求的数据
+	performRequest(engine, http.MethodGet, "/user/info/2?name=jerry")
+	if got := fmt.Sprintf("%s %s %s %v", cp.Param("id"), cp.GetString("user"), cp.GetQuery("name"), cp.Value("user")); got != "1 tom tom tom" {
+		t.Errorf("copy = %q, want %q", got, "1 tom tom tom")
+	}
+	if cp.Err() != nil {
+		t.Errorf("copy Err = %v", cp.Err())
+	}
+}
+
+func TestContext_ResponseWriter(t *testing.T) {
+	engine := New()
+	var status, size int
+	var written bool
+	engine.Use(func(next Handler) Handler {
+		return func(ctx *Context) {
+			if ctx.Written() || ctx.Size() != -1 {
+				t.Errorf("response written before the handler runs")
+			}
+			next(ctx)
+			status, size, written = ctx.Status(), ctx.Size(), ctx.Written()
+		}
+	})
+	g := engine.Group("/resp")
+	g.Get("/json", func(ctx *Context) {
+		ctx.JSON(http.StatusCreated, map[string]int{"id": 1})
+	})
+	g.Get("/html", func(ctx *Context) {
+		ctx.HtmlOptions(http.StatusAccepted, "<b>ok</b>")
+		// 响应头写入后再写入状态码被忽略
+		ctx.W.WriteHeader(http.StatusInternalServerError)
+	})
+	g.Get("/empty", func(ctx *Context) {
+		ctx.W.WriteHeader(http.StatusNoContent)
+	})
+	g.Get("/interfaces", func(ctx *Context) {
+		_, flusher := ctx.W.(http.Flusher)
+		_, hijacker := ctx.W.(http.Hijacker)
+		_, pusher := ctx.W.(http.Pusher)
+		ctx.String(http.StatusOK, "%v %v %v", flusher, hijacker, pusher)
+	})
+
+	tests := []struct {
+		path        string
+		status      int
+		size        int
+		contentType string
+	}{
+		{"/resp/json", http.StatusCreated, len(`{"id":1}`), "application/json; charset=utf-8"},
+		{"/resp/html", http.StatusAccepted, len("<b>ok</b>"), "text/html; charset=utf-8"},
+		{"/resp/empty", http.StatusNoContent, -1, ""},
+		{"/resp/interfaces", http.StatusOK, len("true true true"), "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		w := performRequest(engine, http.MethodGet, tt.path)
+		if w.Code != tt.status || status != tt.status {
+			t.Errorf("%s: code = %d, ctx.Status() = %d, want %d", tt.path, w.Code, status, tt.status)
+		}
+		if size != tt.size || written != (tt.size >= 0) {
+			t.Errorf("%s: ctx.Size() = %d, ctx.Written() = %v, want size %d", tt.path, size, written, tt.size)
+		}
+		if got := w.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.path, got, tt.contentType)
+		}
+	}
+}
+
+type queryAddress struct {
+	City string `query:"city"`
+}
+
+type userQuery struct {
+	queryAddress
+	Name     string            `query:"name" validate:"required"`
+	Age      *int              `form:"age"`
+	Page     int               `query:"page" default:"1"`
+	Size     int               `query:"size" default:"20"`
+	Ids      []int64           `query:"ids"`
+	Tags     []string          `query:"tags" default:"a,b"`
+	Active   bool              `query:"active"`
+	Birthday time.Time         `query:"birthday" time_format:"2006-01-02" time_location:"UTC"`
+	Timeout  time.Duration     `query:"timeout"`
+	Attrs    map[string]string `query:"user"`
+	Filter   struct {
+		Level uint8 `query:"level"`
+	} `query:"filter"`
+	Ignored string `query:"-"`
+}
+
+func TestContext_BindQuery(t *testing.T) {
+	engine := New()
+	var got userQuery
+	var bindErr error
+	engine.Group("/user").Get("/list", func(ctx *Context) {
+		got = userQuery{}
+		bindErr = ctx.BindQuery(&got)
+	})
+
+	performRequest(engine, http.MethodGet, "/user/list?name=tom&age=18&size=5&ids=1&ids=2&active=on"+
+		"&birthday=2000-01-02&timeout=1m30s&user[name]=tom&user[role]=admin&filter[level]=3&city=beijing&Ignored=x")
+	if bindErr != nil {
+		t.Fatalf("BindQuery: %v", bindErr)
+	}
+	want := userQuery{
+		queryAddress: queryAddress{City: "beijing"},
+		Name:         "tom",
+		Page:         1,
+		Size:         5,
+		Ids:          []int64{1, 2},
+		Tags:         []string{"a", "b"},
+		Active:       true,
+		Birthday:     time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		Timeout:      90 * time.Second,
+		Attrs:        map[string]string{"name": "tom", "role": "admin"},
+	}
+	want.Filter.Level = 3
+	if got.Age == nil || *got.Age != 18 {
+		t.Errorf("Age = %v, want 18", got.Age)
+	}
+	got.Age = nil
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("BindQuery =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// 校验失败、类型转换失败时返回错误及 400
+	for _, query := range []string{"page=2", "name=tom&page=x"} {
+		w := performRequest(engine, http.MethodGet, "/user/list?"+query)
+		if bindErr == nil || w.Code != http.StatusBadRequest {
+			t.Errorf("%s: err = %v, code = %d, want an error and 400", query, bindErr, w.Code)
+		}
+	}
+}
+
+type jsonUser struct {
+	Name string `json:"name" validate:"required"`
+	Age  int    `json:"age" validate:"gte=0,lte=150"`
+}
+
+func TestContext_BindJson(t *testing.T) {
+	engine := New()
+	var got jsonUser
+	var bindErr error
+	engine.Group("/user").Post("/add", func(ctx *Context) {
+		got = jsonUser{}
+		bindErr = ctx.BindJson(&got)
+	})
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/user/add", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if post(`{"name":"tom","age":18}`); bindErr != nil || got != (jsonUser{Name: "tom", Age: 18}) {
+		t.Errorf("BindJson = %+v, %v", got, bindErr)
+	}
+	// 按 validate 标签校验，失败时返回错误及 400
+	for _, body := range []string{`{"age":18}`, `{"name":"tom","age":200}`} {
+		if w := post(body); bindErr == nil || w.Code != http.StatusBadRequest {
+			t.Errorf("%s: err = %v, code = %d, want an error and 400", body, bindErr, w.Code)
+		}
+	}
+}
+
+type uploadForm struct {
+	Title  string                  `form:"title" validate:"required"`
+	Tags   []string                `form:"tags"`
+	Avatar *multipart.FileHeader   `form:"avatar" validate:"required"`
+	Photos []*multipart.FileHeader `form:"photos"`
+}
+
+func TestContext_BindForm(t *testing.T) {
+	engine := New()
+	var got uploadForm
+	var bindErr error
+	engine.Group("/user").Post("/upload", func(ctx *Context) {
+		got = uploadForm{}
+		bindErr = ctx.BindForm(&got)
+	})
+
+	var body strings.Builder
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("title", "hello")
+	mw.WriteField("tags", "a")
+	mw.WriteField("tags", "b")
+	for _, f := range []struct{ field, name string }{{"avatar", "a.png"}, {"photos", "1.png"}, {"photos", "2.png"}} {
+		fw, _ := mw.CreateFormFile(f.field, f.name)
+		fw.Write([]byte(f.name))
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/user/upload", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	if bindErr != nil {
+		t.Fatalf("BindForm: %v", bindErr)
+	}
+	if got.Title != "hello" || fmt.Sprint(got.Tags) != "[a b]" {
+		t.Errorf("BindForm = %+v", got)
+	}
+	if got.Avatar == nil || got.Avatar.Filename != "a.png" || len(got.Photos) != 2 || got.Photos[1].Filename != "2.png" {
+		t.Errorf("BindForm files: avatar = %v, photos = %v", got.Avatar, got.Photos)
+	}
+
+	// urlencoded 表单缺少必填的文件时返回错误及 400
+	req = httptest.NewRequest(http.MethodPost, "/user/upload", strings.NewReader(url.Values{"title": {"hello"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if got.Title != "hello" || bindErr == nil || w.Code != http.StatusBadRequest {
+		t.Errorf("urlencoded: title = %q, err = %v, code = %d", got.Title, bindErr, w.Code)
+	}
+}
@@ -2,16 +2,19 @@ package msgo
 
 import (
 	"errors"
-	"fmt"
 	"github.com/kk88183080k/goWeb/msgo/logs"
 	"github.com/kk88183080k/goWeb/msgo/msconf"
 	"github.com/kk88183080k/goWeb/msgo/render"
 	"github.com/kk88183080k/goWeb/msgo/utils"
 	"html/template"
+	"io/fs"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 const ANY = "ANY"
@@ -19,46 +22,95 @@ const ANY = "ANY"
 // 中间件定义 start
 
 type Handler func(ctx *Context)
+
+// MiddlewareFun 中间件，调用 handler 时执行后续的中间件及业务处理函数，不调用时后续的处理函数不执行
+// 注册时转换为处理函数链中的一环，请求时不再生成闭包
+//
+// 执行顺序由外到内为: 全局中间件 > 上级分组的中间件 > 分组的中间件 > 方法级的中间件 > 业务处理函数，
+// 同一级中先添加的中间件在外层，与添加中间件、注册路由的先后无关
+//
+//	engine.Use(A, B)
+//	group := engine.Group("/user")
+//	group.Use(C)
+//	group.Get("/info", handler, D)
+//	// A前 B前 C前 D前 handler D后 C后 B后 A后
 type MiddlewareFun func(handler Handler) Handler
 type ErrorHandlerFun func(err error) (int, any) // 返回http状态码，及返回前端的数据
 
 // 中间件定义 start
 
 type routerGroup struct {
-	Name          string
-	PathMap       map[string]map[string]Handler         // k=/ , v= {k:method, v:Handler=处理函数}
-	MiddlePathMap map[string]map[string][]MiddlewareFun // k=/ , v= {k:method, v: []MiddlewareFun 函数前后执行的中间件}
-	PathMethodMap map[string][]string                   // k = any|get|post  v:= []{"/1"， /2}
-	treeNode      *TreeNode                             // 前缀树
-	middlewares   []MiddlewareFun                       // 中间件
+	Name        string         // 完整的分组前缀，子分组包含上级分组的前缀
+	router      *router        // 分组所属的路由
+	parent      *routerGroup   // 上级分组，顶级分组为 nil
+	children    []*routerGroup // 子分组
+	routes      []*route       // 分组中注册的路由
+	middlewares []middleware   // 中间件
 }
 
+// Use 添加分组级的中间件，对分组及子分组中已注册和之后注册的路由都生效
 func (rg *routerGroup) Use(middles ...MiddlewareFun) {
+	rg.use(nil, middles)
+}
+
+// UseWithRule 添加按 rule 生效的分组级中间件，rule 中的路径相对于分组的前缀
+//
+//	group := engine.Group("/user")
+//	group.UseWithRule(msgo.MiddlewareRule{Exclude: []string{"/login"}, Methods: []string{http.MethodPost}}, auth)
+func (rg *routerGroup) UseWithRule(rule MiddlewareRule, middles ...MiddlewareFun) {
+	rg.use(rule.withPrefix(rg.Name), middles)
+}
+
+// UseExcept 添加分组级的中间件，对 paths 匹配的路由不生效，paths 相对于分组的前缀
+func (rg *routerGroup) UseExcept(middle MiddlewareFun, paths ...string) {
+	rg.UseWithRule(MiddlewareRule{Exclude: paths}, middle)
+}
+
+func (rg *routerGroup) use(rule *MiddlewareRule, middles []MiddlewareFun) {
+	e := rg.router.engine
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	// 添加分组级别的中间件
-	rg.middlewares = append(rg.middlewares, middles...)
+	rg.middlewares = append(rg.middlewares, newMiddlewares(rule, middles)...)
+	// 重新组合分组及子分组中已注册路由的处理函数
+	rg.compose()
 }
 
-func (r *routerGroup) methodHandle(name string, method string, handler Handler, ctx *Context) {
-	// 分组级的中间件执行
-	if r.middlewares != nil {
-		for _, midd := range r.middlewares {
-			handler = midd(handler)
-		}
+func (rg *routerGroup) compose() {
+	for _, rt := range rg.routes {
+		rt.compose()
+	}
+	for _, child := range rg.children {
+		child.compose()
 	}
+}
 
-	// 方法级的中间件执行
-	midds := r.MiddlePathMap[name][method]
-	if midds != nil {
-		for _, m := range midds {
-			handler = m(handler)
+// Group 创建子分组，子分组继承上级分组的前缀，上级分组的中间件在子分组的中间件外层执行
+// 例如 engine.Group("/api").Group("/v1") 的前缀为 /api/v1
+func (rg *routerGroup) Group(groupName string) *routerGroup {
+	e := rg.router.engine
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	name := rg.Name + groupName
+	for _, child := range rg.children {
+		if child.Name == name {
+			return child
 		}
 	}
-	// 最终执行的业务方法
-	handler(ctx)
+	group := &routerGroup{
+		Name:        name,
+		router:      rg.router,
+		parent:      rg,
+		routes:      make([]*route, 0),
+		middlewares: make([]middleware, 0)}
+	rg.children = append(rg.children, group)
+	return group
 }
 
 // groupName 必须以/开头
 func (r *router) Group(groupName string) *routerGroup {
+	r.engine.mu.Lock()
+	defer r.engine.mu.Unlock()
 	// 先从已有的分组中查找
 	for _, routerGroup := range r.RouterGroup {
 		if routerGroup.Name == groupName {
@@ -66,88 +118,365 @@ func (r *router) Group(groupName string) *routerGroup {
 		}
 	}
 	// 没有时再生成一个新的
-	tree := &TreeNode{}
-	tree.name = "/"
 	group := &routerGroup{
-		Name:          groupName,
-		PathMap:       make(map[string]map[string]Handler, 0),
-		MiddlePathMap: make(map[string]map[string][]MiddlewareFun, 0),
-		PathMethodMap: make(map[string][]string, 0),
-		treeNode:      tree,
-		middlewares:   make([]MiddlewareFun, 0)}
+		Name:        groupName,
+		router:      r,
+		routes:      make([]*route, 0),
+		middlewares: make([]middleware, 0)}
 	r.RouterGroup = append(r.RouterGroup, group)
-	// 设置全局中间件
-	group.middlewares = append(group.middlewares, r.middlewares...)
 	return group
 }
 
-// 带*号的路由地址得放在尾部，否则会影响精准匹配
-func (rg *routerGroup) add(method, api string, handlerFn Handler, midFn ...MiddlewareFun) *routerGroup {
-	// 添加函数map
-	_, ok := rg.PathMap[api]
-	if !ok {
-		rg.PathMap[api] = make(map[string]Handler, 0)
-		rg.MiddlePathMap[api] = make(map[string][]MiddlewareFun)
-	}
-	_, ok = rg.PathMap[api][method]
-	if ok {
-		panic("有重复的路由")
-	}
+// 同一位置静态路由优先于参数路由，参数路由优先于带*号的路由，与注册顺序无关
+// 服务启动后仍可注册路由，正在处理的请求不受影响
+func (rg *routerGroup) add(method, api string, handlerFn Handler, midFn ...MiddlewareFun) *RouteHandle {
+	return rg.register(method, api, handlerFn, midFn, false)
+}
 
-	// 设置请求处理的函数
-	rg.PathMap[api][method] = handlerFn
-	// 添加请求方式
-	apiMethodArray, ok := rg.PathMethodMap[method]
-	for _, v := range apiMethodArray {
-		if v == api {
-			panic(api + " method, is exists")
-		}
-	}
-	rg.PathMethodMap[method] = append(apiMethodArray, api)
+// Replace 注册路由，已存在相同的路由时替换为新的处理函数，可在服务启动后调用
+// 被替换的路由的名称失效，需要时在返回的 RouteHandle 上重新调用 Named
+//
+//	plugin.Replace(http.MethodGet, "/status", handler).Named("plugin.status")
+func (rg *routerGroup) Replace(method, api string, handler Handler, midFn ...MiddlewareFun) *RouteHandle {
+	return rg.register(method, api, handler, midFn, true)
+}
+
+// Remove 删除分组中的路由，method 需与注册时一致(ANY 注册的路由使用 ANY 删除)，可在服务启动后调用
+// 返回是否删除了路由
+func (rg *routerGroup) Remove(method, api string) bool {
+	e := rg.router.engine
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return rg.removeLocked(method, api)
+}
 
-	// 添加方法级的中间件
-	if midFn != nil {
-		rg.MiddlePathMap[api][method] = append(rg.MiddlePathMap[api][method], midFn...)
+// removeLocked 删除分组中的路由，调用时持有 engine 的锁
+func (rg *routerGroup) removeLocked(method, api string) bool {
+	e := rg.router.engine
+	path := rg.Name + api
+	if !rg.router.removeRoutes(func(rt *route) bool {
+		return rt.method == method && rt.path == path
+	}) {
+		return false
 	}
+	rg.router.rebuild()
+	// 挂载的子 engine 中删除的路由同时从上级 engine 中删除
+	if rg.router == e.router && e.parent != nil {
+		p := e.parent
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		e.mountGroup.removeLocked(method, path)
+	}
+	return true
+}
 
-	nodeUrl := rg.Name + api
-	rg.treeNode.Put(nodeUrl)
-	//log.Println("tree put :", rg.Name, nodeUrl, api)
+func (rg *routerGroup) register(method, api string, handlerFn Handler, midFn []MiddlewareFun, replace bool) *RouteHandle {
+	e := rg.router.engine
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return &RouteHandle{routerGroup: rg, route: rg.registerLocked(method, api, handlerFn, midFn, replace)}
+}
 
-	return rg
+// RouteHandle 注册路由时返回的句柄，Named、Doc 作用于该句柄对应的路由
+// 嵌入了路由所属的分组，可以继续在分组中注册路由
+//
+//	user.Get("/get/:id", handler).Named("user.get").Doc(msgo.RouteDoc{Summary: "查询用户"})
+type RouteHandle struct {
+	*routerGroup
+	route *route
 }
 
-func (rg *routerGroup) Any(api string, handler Handler, midFn ...MiddlewareFun) *routerGroup {
+// registerLocked 注册路由，调用时持有 engine 的锁
+func (rg *routerGroup) registerLocked(method, api string, handlerFn Handler, midFn []MiddlewareFun, replace bool) *route {
+	e := rg.router.engine
+	rt := &route{
+		method:      method,
+		path:        rg.Name + api,
+		handler:     handlerFn,
+		middlewares: midFn,
+		group:       rg,
+	}
+	rt.compose()
+	rg.router.addRoute(rt, replace)
+	if replace {
+		// 删除请求方式被新路由完全覆盖的旧路由
+		rg.router.removeRoutes(func(old *route) bool {
+			return old.path == rt.path && (old.method == rt.method || rt.method == ANY)
+		})
+	}
+	rg.routes = append(rg.routes, rt)
+	rg.router.routes = append(rg.router.routes, rt)
+	// 挂载的子 engine 中注册的路由同时注册到上级 engine
+	if rg.router == e.router && e.parent != nil {
+		e.mountRoute(rt, replace)
+	}
+	return rt
+}
+
+func (rg *routerGroup) Any(api string, handler Handler, midFn ...MiddlewareFun) *RouteHandle {
 	return rg.add(ANY, api, handler, midFn...)
 }
 
-func (rg *routerGroup) Post(api string, handler Handler, midFn ...MiddlewareFun) *routerGroup {
+func (rg *routerGroup) Post(api string, handler Handler, midFn ...MiddlewareFun) *RouteHandle {
 	return rg.add(http.MethodPost, api, handler, midFn...)
 }
 
-func (rg *routerGroup) Get(api string, handler Handler, midFn ...MiddlewareFun) *routerGroup {
+func (rg *routerGroup) Get(api string, handler Handler, midFn ...MiddlewareFun) *RouteHandle {
 	return rg.add(http.MethodGet, api, handler, midFn...)
 }
 
-func (rg *routerGroup) Put(api string, handler Handler, midFn ...MiddlewareFun) *routerGroup {
+func (rg *routerGroup) Put(api string, handler Handler, midFn ...MiddlewareFun) *RouteHandle {
 	return rg.add(http.MethodPut, api, handler, midFn...)
 }
 
-func (rg *routerGroup) Patch(api string, handler Handler, midFn ...MiddlewareFun) *routerGroup {
+func (rg *routerGroup) Patch(api string, handler Handler, midFn ...MiddlewareFun) *RouteHandle {
 	return rg.add(http.MethodPatch, api, handler, midFn...)
 }
 
-func (rg *routerGroup) Options(api string, handler Handler, midFn ...MiddlewareFun) *routerGroup {
+func (rg *routerGroup) Options(api string, handler Handler, midFn ...MiddlewareFun) *RouteHandle {
 	return rg.add(http.MethodOptions, api, handler, midFn...)
 }
 
-func (rg *routerGroup) Head(api string, handler Handler, midFn ...MiddlewareFun) *routerGroup {
+func (rg *routerGroup) Head(api string, handler Handler, midFn ...MiddlewareFun) *RouteHandle {
 	return rg.add(http.MethodHead, api, handler, midFn...)
 }
 
 type router struct {
+	engine      *Engine
+	host        string   // 路由匹配的 Host，为空时匹配全部
+	hostLabels  []string // Host 按 . 分隔后的各级
+	hostParam   bool     // Host 中是否有参数
 	RouterGroup []*routerGroup
-	middlewares []MiddlewareFun // 中间件
+	middlewares []middleware // 全局中间件，只用于 engine 的路由
+	routes      []*route     // 按注册顺序保存的全部路由
+	// 每种请求方式一棵前缀树，注册、删除路由时生成新的前缀树后原子替换，匹配时不加锁
+	trees atomic.Pointer[methodTrees]
+}
+
+// loadTrees 返回当前的前缀树
+func (r *router) loadTrees() methodTrees {
+	if trees := r.trees.Load(); trees != nil {
+		return *trees
+	}
+	return nil
+}
+
+// addRoute 将路由添加到对应请求方式的前缀树中，replace 为 true 时替换已存在的路由
+// 只复制修改路径上的节点，全部请求方式添加成功后才替换前缀树，重复的路由 panic 时原有的前缀树不变
+func (r *router) addRoute(rt *route, replace bool) {
+	trees := append(methodTrees(nil), r.loadTrees()...)
+	for _, method := range rt.methods() {
+		i := trees.index(method)
+		if i < 0 {
+			trees = append(trees, methodTree{method: method, root: &TreeNode{}})
+			i = len(trees) - 1
+		}
+		root := trees[i].root.clone()
+		leaf := root.Put(rt.path)
+		if leaf.route != nil && !replace {
+			panic(method + " " + rt.path + " 有重复的路由")
+		}
+		leaf.route = rt
+		leaf.handlers = rt.chains[method]
+		trees[i].root = root
+	}
+	r.updateMaxParams(rt)
+	r.trees.Store(&trees)
+}
+
+// removeRoutes 从路由列表、分组及命名路由中删除 match 的路由，不修改前缀树，返回是否删除了路由
+func (r *router) removeRoutes(match func(rt *route) bool) bool {
+	removed := false
+	routes := make([]*route, 0, len(r.routes))
+	for _, rt := range r.routes {
+		if !match(rt) {
+			routes = append(routes, rt)
+			continue
+		}
+		removed = true
+		rt.removed = true
+		rt.group.routes = removeRoute(rt.group.routes, rt)
+		if rt.name != "" && r.engine.namedRoutes[rt.name] == rt {
+			delete(r.engine.namedRoutes, rt.name)
+		}
+	}
+	r.routes = routes
+	return removed
+}
+
+func removeRoute(routes []*route, rt *route) []*route {
+	rs := make([]*route, 0, len(routes))
+	for _, v := range routes {
+		if v != rt {
+			rs = append(rs, v)
+		}
+	}
+	return rs
+}
+
+// rebuild 按路由列表重新生成前缀树后原子替换，后注册的路由覆盖先注册的
+func (r *router) rebuild() {
+	var trees methodTrees
+	for _, rt := range r.routes {
+		for _, method := range rt.methods() {
+			root := trees.get(method)
+			if root == nil {
+				root = &TreeNode{}
+				trees = append(trees, methodTree{method: method, root: root})
+			}
+			leaf := root.Put(rt.path)
+			leaf.route = rt
+			leaf.handlers = rt.chains[method]
+		}
+	}
+	r.trees.Store(&trees)
+}
+
+// updateMaxParams 更新单个路由中路径参数的最大个数，参数个数包括 Host 中的参数
+func (r *router) updateMaxParams(rt *route) {
+	n := int32(countParams(rt.path) + countParams(r.host))
+	if n > r.engine.maxParams.Load() {
+		r.engine.maxParams.Store(n)
+	}
+}
+
+// anyMethods ANY 路由对应的请求方式
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodHead,
+	http.MethodOptions, http.MethodDelete, http.MethodConnect, http.MethodTrace,
+}
+
+type methodTree struct {
+	method string
+	root   *TreeNode
+}
+
+type methodTrees []methodTree
+
+func (trees methodTrees) get(method string) *TreeNode {
+	if i := trees.index(method); i >= 0 {
+		return trees[i].root
+	}
+	return nil
+}
+
+func (trees methodTrees) index(method string) int {
+	for i, tree := range trees {
+		if tree.method == method {
+			return i
+		}
+	}
+	return -1
+}
+
+// route 注册的单个路由
+type route struct {
+	method      string                   // 请求方式，ANY 表示全部
+	path        string                   // 完整路由 = 分组名 + api
+	handler     Handler                  // 业务处理函数
+	middlewares []MiddlewareFun          // 方法级的中间件
+	group       *routerGroup             // 所属的分组
+	name        string                   // 路由名称
+	mounted     *route                   // 挂载的子 engine 中的路由在上级 engine 中对应的路由
+	source      *route                   // 上级 engine 中挂载的路由对应的子 engine 中的路由
+	removed     bool                     // 路由已被删除或替换
+	chains      map[string]*handlerChain // 每种请求方式由外到内的中间件及业务处理函数组成的处理函数链，ANY 路由按请求方式分别组合
+	doc         *RouteDoc                // 文档信息
+}
+
+// methods 路由对应的请求方式，ANY 对应全部请求方式
+func (rt *route) methods() []string {
+	if rt.method == ANY {
+		return anyMethods
+	}
+	return []string{rt.method}
+}
+
+// compose 将全局、分组级(包括上级分组)、方法级的中间件与业务处理函数组合为处理函数链
+// 执行顺序见 MiddlewareFun，带生效范围的中间件在此时按路由及请求方式筛选，ANY 路由对每种请求方式分别组合
+func (rt *route) compose() {
+	if rt.chains == nil {
+		rt.chains = make(map[string]*handlerChain, len(rt.methods()))
+		for _, method := range rt.methods() {
+			rt.chains[method] = &handlerChain{}
+		}
+	}
+	// 分组级的中间件，上级分组的中间件在外层
+	var groups []*routerGroup
+	for group := rt.group; group != nil; group = group.parent {
+		groups = append(groups, group)
+	}
+	for _, method := range rt.methods() {
+		var middlewares []MiddlewareFun
+		add := func(ms []middleware) {
+			for _, m := range ms {
+				if m.match(method, rt.path) {
+					middlewares = append(middlewares, m.fn)
+				}
+			}
+		}
+		// 全局中间件
+		add(rt.group.router.engine.router.middlewares)
+		for i := len(groups) - 1; i >= 0; i-- {
+			add(groups[i].middlewares)
+		}
+		// 方法级的中间件
+		middlewares = append(middlewares, rt.middlewares...)
+		rt.chains[method].store(chainHandlers(middlewares, rt.handler))
+	}
+}
+
+// chain 返回请求方式 method 对应的处理函数链，没有对应的请求方式时(如 GET 路由处理 HEAD 请求)返回注册时请求方式的处理函数链
+func (rt *route) chain(method string) []Handler {
+	if c := rt.chains[method]; c != nil {
+		return c.load()
+	}
+	return rt.chains[rt.methods()[0]].load()
+}
+
+// handlerChain 处理函数链，注册中间件时重新组合后原子替换，处理请求时无锁读取
+type handlerChain struct {
+	p atomic.Pointer[[]Handler]
+}
+
+func (c *handlerChain) load() []Handler {
+	if handlers := c.p.Load(); handlers != nil {
+		return *handlers
+	}
+	return nil
+}
+
+func (c *handlerChain) store(handlers []Handler) {
+	c.p.Store(&handlers)
+}
+
+// nextHandler 中间件中的 next，执行处理函数链中后续的处理函数
+var nextHandler Handler = func(ctx *Context) {
+	ctx.Next()
+}
+
+// chainHandlers 将由外到内的中间件与处理函数组合为处理函数链
+func chainHandlers(middlewares []MiddlewareFun, handler Handler) []Handler {
+	handlers := make([]Handler, 0, len(middlewares)+1)
+	for _, m := range middlewares {
+		handlers = append(handlers, m(nextHandler))
+	}
+	return append(handlers, handler)
+}
+
+// HandlerMiddleware 将调用 ctx.Next() 的处理函数作为中间件使用
+//
+//	group.Use(msgo.HandlerMiddleware(func(ctx *msgo.Context) {
+//		if ctx.R.Header.Get("Authorization") == "" {
+//			ctx.AbortWithStatusJSON(http.StatusUnauthorized, msgo.DefaultR().Fail(401, "未登录").Response())
+//			return
+//		}
+//		ctx.Next()
+//	}))
+func HandlerMiddleware(h Handler) MiddlewareFun {
+	return func(Handler) Handler {
+		return h
+	}
 }
 
 type Engine struct {
@@ -157,14 +486,46 @@ type Engine struct {
 	pool       sync.Pool
 	logger     *logs.Logger
 	errHandler ErrorHandlerFun
+
+	mu          sync.RWMutex              // 保护路由、分组的注册及删除
+	parent      *Engine                   // 挂载到的上级 engine
+	prefix      string                    // 挂载到上级 engine 的路径
+	mountGroup  *routerGroup              // 上级 engine 中挂载子 engine 路由的分组
+	namedRoutes map[string]*route         // 命名的路由
+	hosts       atomic.Pointer[[]*router] // 按 Host 匹配的路由，添加时生成新的列表后原子替换
+	maxParams   atomic.Int32              // 单个路由中路径参数的最大个数
+
+	noRoute          Handler      // 路由不存在时的处理函数
+	noMethod         Handler      // 路由存在但请求方式不允许时的处理函数
+	options          Handler      // 自动处理 OPTIONS 请求的处理函数
+	noRouteHandlers  handlerChain // 组合了全局中间件的 noRoute
+	noMethodHandlers handlerChain // 组合了全局中间件的 noMethod
+	optionsHandlers  handlerChain // 组合了全局中间件的 options
+
+	HandleHEAD    bool // 没有注册 HEAD 路由时，使用 GET 路由处理 HEAD 请求，默认开启
+	HandleOPTIONS bool // 没有注册 OPTIONS 路由时，自动响应 OPTIONS 请求，默认开启
+
+	// RedirectTrailingSlash 路由不存在，但去掉(或加上)末尾的 / 后存在时，重定向到存在的路由，默认开启
+	// 例如 /user/info/ 重定向到 /user/info，GET 请求返回 301，其他请求返回 308
+	RedirectTrailingSlash bool
+	// RedirectFixedPath 路由不存在时，规范化路径(去掉多余的 /、处理 . 和 ..)并忽略大小写匹配，
+	// 匹配到时重定向到注册的路由，默认关闭
+	// 例如 /USER//info 重定向到 /user/info
+	RedirectFixedPath bool
+
+	// Debug 调试模式，开启时 Start 前打印已注册的路由表，默认关闭
+	Debug bool
 }
 
 func New() *Engine {
 	r := &router{RouterGroup: []*routerGroup{}}
-	e := &Engine{router: r, fnMap: template.FuncMap{}, logger: logs.Default()}
+	e := &Engine{router: r, fnMap: template.FuncMap{}, logger: logs.Default(), namedRoutes: map[string]*route{},
+		HandleHEAD: true, HandleOPTIONS: true, RedirectTrailingSlash: true}
+	r.engine = e
+	e.fnMap["urlFor"] = e.URLFor
 	e.pool.New = func() any {
 		log.Println("create Context success")
-		return &Context{e: e}
+		return &Context{e: e, params: make(Params, 0, e.maxParams.Load())}
 	}
 	e.errHandler = func(err error) (int, any) {
 		switch er := err.(type) {
@@ -174,6 +535,16 @@ func New() *Engine {
 			return http.StatusInternalServerError, "Internal Server Error"
 		}
 	}
+	e.noRoute = func(ctx *Context) {
+		ctx.String(http.StatusNotFound, "%v not find", http.StatusNotFound)
+	}
+	e.noMethod = func(ctx *Context) {
+		ctx.String(http.StatusMethodNotAllowed, "%s %s not allowed \n", ctx.R.RequestURI, ctx.R.Method)
+	}
+	e.options = func(ctx *Context) {
+		ctx.W.WriteHeader(http.StatusNoContent)
+	}
+	e.composeGlobal()
 
 	// 根据配置设置日志文件所在的目录
 	logPath, ok := msconf.Conf.Log["path"]
@@ -189,8 +560,15 @@ func Default() *Engine {
 	return New().Use(Recovery, Logging)
 }
 
+// SetFnMap 设置模板函数，未设置 urlFor 时使用默认的 Engine.URLFor
+// fnMap 会被复制，不会修改调用方的 map，为 nil 时只包含 urlFor
 func (e *Engine) SetFnMap(fnMap template.FuncMap) {
-	e.fnMap = fnMap
+	fns := make(template.FuncMap, len(fnMap)+1)
+	fns["urlFor"] = e.URLFor
+	for name, fn := range fnMap {
+		fns[name] = fn
+	}
+	e.fnMap = fns
 }
 
 func (e *Engine) SetRender(t *template.Template) {
@@ -211,6 +589,64 @@ func (e *Engine) LoadTemplateByConf() {
 	e.SetRender(t)
 }
 
+// LoadTemplateFS 从文件系统 fsys(如 embed.FS) 中加载模板
+func (e *Engine) LoadTemplateFS(fsys fs.FS, patterns ...string) {
+	t := template.Must(template.New("").Funcs(e.fnMap).ParseFS(fsys, patterns...))
+	e.SetRender(t)
+}
+
+// LoadTemplateByConfFS 按配置加载模板，[fs] source 为 embed 时从 fsys 中加载，否则从磁盘加载
+func (e *Engine) LoadTemplateByConfFS(fsys fs.FS) {
+	if msconf.Conf.FsSource() != msconf.FsEmbed {
+		e.LoadTemplateByConf()
+		return
+	}
+	confPattern, ok := msconf.Conf.Template["pattern"]
+	if !ok {
+		panic(errors.New("Template pattern is not config "))
+	}
+	e.LoadTemplateFS(fsys, confPattern.(string))
+}
+
+// NoRoute 设置路由不存在(404)时的处理函数，处理函数会经过全局中间件
+func (e *Engine) NoRoute(handler Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.noRoute = handler
+	e.composeGlobal()
+}
+
+// NoMethod 设置路由存在但请求方式不允许(405)时的处理函数，处理函数会经过全局中间件
+// 执行前响应头 Allow 中已设置了该路由允许的请求方式
+func (e *Engine) NoMethod(handler Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.noMethod = handler
+	e.composeGlobal()
+}
+
+// GlobalOptions 设置自动响应 OPTIONS 请求的处理函数，处理函数会经过全局中间件
+// 执行前响应头 Allow 中已设置了该路由允许的请求方式，默认返回 204
+func (e *Engine) GlobalOptions(handler Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.options = handler
+	e.composeGlobal()
+}
+
+// composeGlobal 将全局中间件与 noRoute、noMethod、options 组合，调用时持有 engine 的锁
+func (e *Engine) composeGlobal() {
+	var middlewares []MiddlewareFun
+	for _, m := range e.router.middlewares {
+		if m.global() {
+			middlewares = append(middlewares, m.fn)
+		}
+	}
+	e.noRouteHandlers.store(chainHandlers(middlewares, e.noRoute))
+	e.noMethodHandlers.store(chainHandlers(middlewares, e.noMethod))
+	e.optionsHandlers.store(chainHandlers(middlewares, e.options))
+}
+
 func (e *Engine) RegisterErrorHandler(handler ErrorHandlerFun) {
 	e.errHandler = handler
 }
@@ -218,7 +654,8 @@ func (e *Engine) RegisterErrorHandler(handler ErrorHandlerFun) {
 func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	context := e.pool.Get().(*Context)
 	// 设置初始值，否则会缓存
-	context.W = w
+	context.writer.reset(w)
+	context.W = &context.writer
 	context.R = r
 	context.queryCache = nil
 	context.formCache = nil
@@ -226,51 +663,169 @@ func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	context.IsValidate = false
 	context.StatusCode = -1
 	context.Logger = e.logger
+	if maxParams := int(e.maxParams.Load()); cap(context.params) < maxParams {
+		context.params = make(Params, 0, maxParams)
+	}
+	context.params = context.params[:0]
+	context.handlers = nil
+	context.index = 0
 
 	e.severHttpRequestHandle(context)
+	// 只调用了 WriteHeader 没有写入响应体时，写入响应头
+	context.writer.WriteHeaderNow()
 
+	// 放回池中前清空请求内的数据，避免下一个请求读取到，也避免池中的 Context 持有已结束请求的数据
+	context.keysMu.Lock()
+	context.keys = nil
+	context.keysMu.Unlock()
 	e.pool.Put(context)
 }
 
 func (e *Engine) severHttpRequestHandle(ctx *Context) {
 	r := ctx.R
-	w := ctx.W
 	method := r.Method
 	path := r.URL.Path
-	for _, rg := range e.RouterGroup {
-		node := rg.treeNode.Get(path)
-		//log.Printf("tree get, rg.Name:%s, urlPath: %s, treeNode: %v\n", rg.Name, path, node)
-
-		if node != nil && node.leaf {
-			apiUrl := utils.SubStringLast(node.routerFullPath, rg.Name)
-			//log.Printf("method match: %v\n", apiUrl)
-			// 先匹配any的
-			handle, ok := rg.PathMap[apiUrl][ANY]
-			if ok {
-				//handle(ctx)
-				rg.methodHandle(apiUrl, ANY, handle, ctx)
-				return
-			}
-			// 再匹配其他的
-			handle, ok = rg.PathMap[apiUrl][method]
-			if ok {
-				//handle(ctx)
-				rg.methodHandle(apiUrl, ANY, handle, ctx)
+	rt := e.matchHost(ctx)
+	// Host 中捕获的参数
+	hostParams := len(ctx.params)
+	trees := rt.loadTrees()
+	if root := trees.get(method); root != nil {
+		if node := root.getValue(path, &ctx.params); node != nil {
+			ctx.run(node.handlers.load())
+			return
+		}
+		ctx.params = ctx.params[:hostParams]
+	}
+
+	// HEAD 请求使用 GET 路由处理，不返回响应体
+	if method == http.MethodHead && e.HandleHEAD {
+		if root := trees.get(http.MethodGet); root != nil {
+			if node := root.getValue(path, &ctx.params); node != nil {
+				ctx.writer.discard = true
+				ctx.run(node.handlers.load())
 				return
 			}
+			ctx.params = ctx.params[:hostParams]
+		}
+	}
 
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			fmt.Fprintf(w, "%s %s not allowed \n", r.RequestURI, method)
+	// 重定向到规范的路由
+	if method != http.MethodConnect && (e.RedirectTrailingSlash || e.RedirectFixedPath) {
+		if fixedPath, ok := e.fixedPath(rt, method, path); ok {
+			e.redirect(ctx, fixedPath)
 			return
 		}
 	}
 
-	w.WriteHeader(http.StatusNotFound)
-	fmt.Fprintf(w, "%v not find", http.StatusNotFound)
+	allowed := e.allowedMethods(rt, path)
+	// OPTIONS 请求返回该路由允许的请求方式
+	if method == http.MethodOptions && e.HandleOPTIONS && len(allowed) > 0 {
+		ctx.W.Header().Set("Allow", strings.Join(allowed, ", "))
+		ctx.run(e.optionsHandlers.load())
+		return
+	}
+
+	// 其他请求方式中存在该路由时返回 405
+	if len(allowed) > 0 {
+		ctx.W.Header().Set("Allow", strings.Join(allowed, ", "))
+		ctx.run(e.noMethodHandlers.load())
+		return
+	}
+
+	ctx.run(e.noRouteHandlers.load())
+}
+
+// fixedPath 按 RedirectTrailingSlash、RedirectFixedPath 查找 path 对应的已注册路由的路径
+func (e *Engine) fixedPath(rt *router, method, path string) (string, bool) {
+	roots := []*TreeNode{rt.loadTrees().get(method)}
+	if method == http.MethodHead && e.HandleHEAD {
+		roots = append(roots, rt.loadTrees().get(http.MethodGet))
+	}
+
+	var params Params
+	for _, root := range roots {
+		if root == nil {
+			continue
+		}
+		if e.RedirectTrailingSlash && path != "/" {
+			tsrPath := toggleTrailingSlash(path)
+			if root.getValue(tsrPath, &params) != nil {
+				return tsrPath, true
+			}
+			params = params[:0]
+		}
+		if e.RedirectFixedPath {
+			cleanPath := utils.CleanPath(path)
+			if rs, ok := root.findCaseInsensitive(cleanPath, make([]byte, 0, len(cleanPath)+1)); ok && string(rs) != path {
+				return string(rs), true
+			}
+			if e.RedirectTrailingSlash && cleanPath != "/" {
+				cleanPath = toggleTrailingSlash(cleanPath)
+				if rs, ok := root.findCaseInsensitive(cleanPath, make([]byte, 0, len(cleanPath))); ok {
+					return string(rs), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func toggleTrailingSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return path[:len(path)-1]
+	}
+	return path + "/"
+}
+
+// redirect 重定向到 path，保留请求参数，GET、HEAD 请求返回 301，其他请求返回 308
+func (e *Engine) redirect(ctx *Context, path string) {
+	code := http.StatusPermanentRedirect
+	if ctx.R.Method == http.MethodGet || ctx.R.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	if ctx.R.URL.RawQuery != "" {
+		path += "?" + ctx.R.URL.RawQuery
+	}
+	http.Redirect(ctx.W, ctx.R, path, code)
+}
+
+// allowedMethods 返回 path 匹配的路由所允许的请求方式，按字母排序
+// 包括自动处理的 HEAD、OPTIONS
+func (e *Engine) allowedMethods(rt *router, path string) []string {
+	var allowed []string
+	var params Params
+	for _, tree := range rt.loadTrees() {
+		if tree.root.getValue(path, &params) != nil {
+			allowed = append(allowed, tree.method)
+		}
+		params = params[:0]
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	if e.HandleHEAD && containsString(allowed, http.MethodGet) && !containsString(allowed, http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if e.HandleOPTIONS && !containsString(allowed, http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+func containsString(arr []string, s string) bool {
+	for _, v := range arr {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 func (e *Engine) Start(ip string, port int) {
 	localAddress := ip + ":" + strconv.Itoa(port)
+	e.printRoutes()
 	log.Printf("start server by : http://%s\n", localAddress)
 	err := http.ListenAndServe(localAddress, e)
 	if err != nil {
@@ -280,6 +835,7 @@ func (e *Engine) Start(ip string, port int) {
 
 // StartByTLS https 支持
 func (e *Engine) StartByTLS(addr, certFile, keyFile string) {
+	e.printRoutes()
 	log.Printf("start server by : https://%s\n", addr)
 	err := http.ListenAndServeTLS(addr, certFile, keyFile, e)
 	if err != nil {
@@ -287,7 +843,37 @@ func (e *Engine) StartByTLS(addr, certFile, keyFile string) {
 	}
 }
 
+// Use 添加全局中间件，对全部路由(包括按 Host 匹配的路由)及 NoRoute、NoMethod、OPTIONS 的处理函数生效，
+// 与分组的创建、路由的注册先后无关
 func (e *Engine) Use(fn ...MiddlewareFun) *Engine {
-	e.router.middlewares = append(e.router.middlewares, fn...)
+	return e.use(nil, fn)
+}
+
+// UseWithRule 添加按 rule 生效的全局中间件，rule 中的路径为完整路由
+// 只有 Exclude 规则时同样对 NoRoute、NoMethod、OPTIONS 的处理函数生效
+//
+//	engine.UseWithRule(msgo.MiddlewareRule{Include: []string{"/api"}, Methods: []string{http.MethodPost}}, audit)
+func (e *Engine) UseWithRule(rule MiddlewareRule, fn ...MiddlewareFun) *Engine {
+	return e.use(&rule, fn)
+}
+
+// UseExcept 添加全局中间件，对 paths 匹配的路由不生效
+//
+//	engine.UseExcept(msgo.Logging, "/health", "/assets/**")
+func (e *Engine) UseExcept(fn MiddlewareFun, paths ...string) *Engine {
+	return e.UseWithRule(MiddlewareRule{Exclude: paths}, fn)
+}
+
+func (e *Engine) use(rule *MiddlewareRule, fn []MiddlewareFun) *Engine {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.router.middlewares = append(e.router.middlewares, newMiddlewares(rule, fn)...)
+	e.composeGlobal()
+	for _, r := range append([]*router{e.router}, e.hostRouters()...) {
+		for _, rt := range r.routes {
+			rt.compose()
+		}
+	}
 	return e
 }
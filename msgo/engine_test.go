@@ -0,0 +1,871 @@
+package msgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/kk88183080k/goWeb/msgo/logs"
+	"github.com/kk88183080k/goWeb/msgo/openapi"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// performRequest 执行请求，返回响应
+func performRequest(e *Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestEngine_ServeHTTP(t *testing.T) {
+	engine := New()
+	user := engine.Group("/user")
+	user.Get("/get/:id", func(ctx *Context) {
+		ctx.String(http.StatusOK, "get %s", ctx.Param("id"))
+	})
+	user.Get("/search", func(ctx *Context) {
+		ctx.String(http.StatusOK, "search")
+	})
+	user.Post("/save", func(ctx *Context) {
+		ctx.String(http.StatusOK, "save")
+	}, func(next Handler) Handler {
+		return func(ctx *Context) {
+			ctx.W.Header().Set("X-Route", "save")
+			next(ctx)
+		}
+	})
+	engine.Group("/").Any("index", func(ctx *Context) {
+		ctx.String(http.StatusOK, "index %s", ctx.R.Method)
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{http.MethodGet, "/user/get/10", http.StatusOK, "get 10"},
+		{http.MethodGet, "/user/search", http.StatusOK, "search"},
+		{http.MethodPost, "/user/save", http.StatusOK, "save"},
+		{http.MethodDelete, "/index", http.StatusOK, "index DELETE"},
+		{http.MethodPost, "/user/search", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/user/none", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := performRequest(engine, tt.method, tt.path)
+		if w.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, w.Code, tt.status)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s %s: body = %q, want %q", tt.method, tt.path, w.Body.String(), tt.body)
+		}
+	}
+
+	if w := performRequest(engine, http.MethodPost, "/user/save"); w.Header().Get("X-Route") != "save" {
+		t.Errorf("route middleware not applied")
+	}
+}
+
+func TestEngine_DuplicateRoute(t *testing.T) {
+	engine := New()
+	engine.Group("/user").Get("/:id", func(ctx *Context) {})
+	defer func() {
+		if err := recover(); err == nil {
+			t.Errorf("want panic")
+		}
+	}()
+	engine.Group("/").Get("user/:id", func(ctx *Context) {})
+}
+
+func TestEngine_ParamConstraint(t *testing.T) {
+	engine := New()
+	user := engine.Group("/user")
+	user.Get("/del/:id<int>", func(ctx *Context) {
+		id, _ := ctx.ParamInt("id")
+		ctx.String(http.StatusOK, "del %d", id+1)
+	})
+
+	if w := performRequest(engine, http.MethodGet, "/user/del/41"); w.Body.String() != "del 42" {
+		t.Errorf("body = %q, want %q", w.Body.String(), "del 42")
+	}
+	if w := performRequest(engine, http.MethodGet, "/user/del/abc"); w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestRouterGroup_Group(t *testing.T) {
+	engine := New()
+	header := func(key, val string) MiddlewareFun {
+		return func(next Handler) Handler {
+			return func(ctx *Context) {
+				ctx.W.Header().Add(key, val)
+				next(ctx)
+			}
+		}
+	}
+	handler := func(ctx *Context) {
+		ctx.String(http.StatusOK, ctx.R.URL.Path)
+	}
+
+	api := engine.Group("/api")
+	v1 := api.Group("/v1")
+	v1.Get("/user", handler)
+	admin := v1.Group("/admin")
+	admin.Get("/user", handler)
+	// 在路由注册之后添加的中间件同样生效
+	api.Use(header("X-Group", "api"))
+	admin.Use(header("X-Admin", "true"))
+
+	w := performRequest(engine, http.MethodGet, "/api/v1/user")
+	if w.Body.String() != "/api/v1/user" || w.Header().Get("X-Group") != "api" || w.Header().Get("X-Admin") != "" {
+		t.Errorf("/api/v1/user: body = %q, header = %v", w.Body.String(), w.Header())
+	}
+	w = performRequest(engine, http.MethodGet, "/api/v1/admin/user")
+	if w.Body.String() != "/api/v1/admin/user" || w.Header().Get("X-Group") != "api" || w.Header().Get("X-Admin") != "true" {
+		t.Errorf("/api/v1/admin/user: body = %q, header = %v", w.Body.String(), w.Header())
+	}
+	if api.Group("/v1") != v1 {
+		t.Errorf("Group should return the existing sub group")
+	}
+}
+
+func TestEngine_NoRoute_NoMethod(t *testing.T) {
+	engine := New()
+	engine.Group("/user").Get("/info", func(ctx *Context) {})
+	engine.Group("/user").Put("/info", func(ctx *Context) {})
+	engine.Use(func(next Handler) Handler {
+		return func(ctx *Context) {
+			ctx.W.Header().Set("X-Global", "true")
+			next(ctx)
+		}
+	})
+	engine.NoRoute(func(ctx *Context) {
+		ctx.JSON(http.StatusNotFound, DefaultR().Fail(404, "not found").Response())
+	})
+	engine.NoMethod(func(ctx *Context) {
+		ctx.JSON(http.StatusMethodNotAllowed, DefaultR().Fail(405, "method not allowed").Response())
+	})
+
+	w := performRequest(engine, http.MethodGet, "/user/none")
+	if w.Code != http.StatusNotFound || w.Body.String() != `{"code":404,"msg":"not found"}` || w.Header().Get("X-Global") != "true" {
+		t.Errorf("404: status = %d, body = %q, header = %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = performRequest(engine, http.MethodPost, "/user/info")
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != `{"code":405,"msg":"method not allowed"}` || w.Header().Get("X-Global") != "true" {
+		t.Errorf("405: status = %d, body = %q, header = %v", w.Code, w.Body.String(), w.Header())
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, PUT" {
+		t.Errorf("405: Allow = %q, want %q", allow, "GET, HEAD, OPTIONS, PUT")
+	}
+
+	// 处理请求的同时修改 NoRoute，使用 go test -race 检查
+	engine.Group("/").StaticFS("assets", http.Dir("."))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				performRequest(engine, http.MethodGet, "/user/none")
+				performRequest(engine, http.MethodGet, "/assets/none.txt")
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		engine.NoRoute(func(ctx *Context) {
+			ctx.String(http.StatusNotFound, "none")
+		})
+	}
+	wg.Wait()
+	if w := performRequest(engine, http.MethodGet, "/assets/none.txt"); w.Code != http.StatusNotFound || w.Body.String() != "none" {
+		t.Errorf("static 404: status = %d, body = %q", w.Code, w.Body.String())
+	}
+}
+
+func TestEngine_HEAD_OPTIONS(t *testing.T) {
+	engine := New()
+	user := engine.Group("/user")
+	user.Get("/info", func(ctx *Context) {
+		ctx.W.Header().Set("X-User", "1")
+		if _, ok := ctx.W.(http.Flusher); !ok {
+			ctx.W.Header().Set("X-Flusher", "false")
+		}
+		ctx.String(http.StatusOK, "user info")
+	})
+	user.Post("/info", func(ctx *Context) {})
+
+	w := performRequest(engine, http.MethodHead, "/user/info")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("X-User") != "1" || w.Header().Get("X-Flusher") != "" {
+		t.Errorf("HEAD: status = %d, body = %q, header = %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = performRequest(engine, http.MethodOptions, "/user/info")
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("OPTIONS: status = %d, header = %v", w.Code, w.Header())
+	}
+	if w = performRequest(engine, http.MethodOptions, "/user/none"); w.Code != http.StatusNotFound {
+		t.Errorf("OPTIONS /user/none: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	engine.HandleHEAD = false
+	engine.HandleOPTIONS = false
+	if w = performRequest(engine, http.MethodHead, "/user/info"); w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("HEAD disabled: status = %d, header = %v", w.Code, w.Header())
+	}
+	if w = performRequest(engine, http.MethodOptions, "/user/info"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("OPTIONS disabled: status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestEngine_RedirectPath(t *testing.T) {
+	engine := New()
+	user := engine.Group("/user")
+	user.Get("/info", func(ctx *Context) {})
+	user.Post("/list/", func(ctx *Context) {})
+	user.Get("/get/:name", func(ctx *Context) {})
+
+	tests := []struct {
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{http.MethodGet, "/user/info/", http.StatusMovedPermanently, "/user/info"},
+		{http.MethodGet, "/user/info/?id=1", http.StatusMovedPermanently, "/user/info?id=1"},
+		{http.MethodHead, "/user/info/", http.StatusMovedPermanently, "/user/info"},
+		{http.MethodPost, "/user/list", http.StatusPermanentRedirect, "/user/list/"},
+		{http.MethodGet, "/USER/Info", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := performRequest(engine, tt.method, tt.path)
+		if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s: status = %d, location = %q", tt.method, tt.path, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	engine.RedirectFixedPath = true
+	tests = []struct {
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{http.MethodGet, "/USER/Info", http.StatusMovedPermanently, "/user/info"},
+		{http.MethodGet, "/user//info/", http.StatusMovedPermanently, "/user/info"},
+		{http.MethodGet, "/user/list/../info", http.StatusMovedPermanently, "/user/info"},
+		{http.MethodGet, "/User/Get/Tom", http.StatusMovedPermanently, "/user/get/Tom"},
+		{http.MethodPost, "/USER/LIST", http.StatusPermanentRedirect, "/user/list/"},
+	}
+	for _, tt := range tests {
+		w := performRequest(engine, tt.method, tt.path)
+		if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s: status = %d, location = %q", tt.method, tt.path, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestEngine_URLFor(t *testing.T) {
+	engine := New()
+	user := engine.Group("/user")
+	user.Get("/info", func(ctx *Context) {}).Named("user.info")
+	user.Get("/get/:id<int>", func(ctx *Context) {}).Named("user.get")
+	engine.Group("/assets").Get("/**", func(ctx *Context) {}).Named("assets")
+
+	tests := []struct {
+		name   string
+		params []any
+		url    string
+	}{
+		{"user.info", nil, "/user/info"},
+		{"user.get", []any{"id", 10}, "/user/get/10"},
+		{"user.get", []any{"id", 10, "tab", "a b"}, "/user/get/10?tab=a+b"},
+		{"assets", []any{"**", "css/index 1.css"}, "/assets/css/index%201.css"},
+	}
+	for _, tt := range tests {
+		url, err := engine.URLFor(tt.name, tt.params...)
+		if err != nil || url != tt.url {
+			t.Errorf("URLFor(%s, %v) = %q, %v, want %q", tt.name, tt.params, url, err, tt.url)
+		}
+	}
+
+	for _, params := range [][]any{{}, {"id"}, {"id", "abc"}} {
+		if _, err := engine.URLFor("user.get", params...); err == nil {
+			t.Errorf("URLFor(user.get, %v): want error", params)
+		}
+	}
+	if _, err := engine.URLFor("none"); err == nil {
+		t.Errorf("URLFor(none): want error")
+	}
+
+	tpl := template.Must(template.New("").Funcs(engine.fnMap).Parse(`<a href="{{urlFor "user.get" "id" .}}">`))
+	var sb strings.Builder
+	if err := tpl.Execute(&sb, 7); err != nil || sb.String() != `<a href="/user/get/7">` {
+		t.Errorf("template urlFor = %q, %v", sb.String(), err)
+	}
+
+	// Named 作用于注册时返回的路由，与分组中后注册的路由无关，路由删除后 panic
+	g := engine.Group("/g")
+	a := g.Get("/a", func(ctx *Context) {})
+	b := g.Get("/b", func(ctx *Context) {})
+	a.Named("g.a")
+	if url, err := engine.URLFor("g.a"); err != nil || url != "/g/a" {
+		t.Errorf("URLFor(g.a) = %q, %v", url, err)
+	}
+	g.Remove(http.MethodGet, "/b")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Named on a removed route should panic")
+			}
+		}()
+		b.Named("g.b")
+	}()
+	if _, err := engine.URLFor("g.b"); err == nil {
+		t.Errorf("URLFor(g.b): want error")
+	}
+
+	// SetFnMap 复制调用方的 map，nil 时只包含 urlFor
+	fnMap := template.FuncMap{"upper": strings.ToUpper}
+	engine.SetFnMap(fnMap)
+	if _, ok := fnMap["urlFor"]; ok || engine.fnMap["upper"] == nil || engine.fnMap["urlFor"] == nil {
+		t.Errorf("SetFnMap: caller map = %v, engine map = %v", fnMap, engine.fnMap)
+	}
+	engine.SetFnMap(nil)
+	if len(engine.fnMap) != 1 || engine.fnMap["urlFor"] == nil {
+		t.Errorf("SetFnMap(nil): engine map = %v", engine.fnMap)
+	}
+}
+
+func userInfoHandler(ctx *Context) {}
+
+func TestEngine_Routes(t *testing.T) {
+	engine := New()
+	noop := func(next Handler) Handler { return next }
+	user := engine.Group("/user")
+	user.Use(noop)
+	user.Get("/info", userInfoHandler, noop).Named("user.info")
+	user.Group("/admin").Any("/list", userInfoHandler)
+	engine.Group("/debug").Get("/routes", engine.RoutesHandler())
+
+	routes := engine.Routes()
+	want := []RouteInfo{
+		{Method: http.MethodGet, Path: "/user/info", Handler: "github.com/kk88183080k/goWeb/msgo.userInfoHandler", Middlewares: 2, Name: "user.info"},
+		{Method: ANY, Path: "/user/admin/list", Handler: "github.com/kk88183080k/goWeb/msgo.userInfoHandler", Middlewares: 1},
+	}
+	if len(routes) != 3 {
+		t.Fatalf("len(Routes()) = %d, want 3", len(routes))
+	}
+	for i, rt := range want {
+		if routes[i] != rt {
+			t.Errorf("Routes()[%d] = %+v, want %+v", i, routes[i], rt)
+		}
+	}
+
+	w := performRequest(engine, http.MethodGet, "/debug/routes")
+	if !strings.Contains(w.Body.String(), `{"method":"GET","path":"/user/info","handler":"github.com/kk88183080k/goWeb/msgo.userInfoHandler","middlewares":2,"name":"user.info"}`) {
+		t.Errorf("/debug/routes: body = %s", w.Body.String())
+	}
+
+	// 只在调试模式时打印路由表
+	out, err := os.Create(filepath.Join(t.TempDir(), "routes.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	engine.logger.Outs = []*logs.LogWriter{{Level: -1, Out: out}}
+	engine.printRoutes()
+	if b, _ := os.ReadFile(out.Name()); len(b) != 0 {
+		t.Errorf("printRoutes without Debug = %q, want nothing", b)
+	}
+	engine.Debug = true
+	engine.printRoutes()
+	if b, _ := os.ReadFile(out.Name()); !strings.Contains(string(b), "/user/admin/list") {
+		t.Errorf("printRoutes with Debug = %q", b)
+	}
+}
+
+func TestEngine_Host(t *testing.T) {
+	engine := New()
+	handler := func(name string) Handler {
+		return func(ctx *Context) {
+			ctx.String(http.StatusOK, "%s %s %s", name, ctx.Param("tenant"), ctx.Param("id"))
+		}
+	}
+	engine.Group("/user").Get("/:id", handler("public"))
+	var admin HostRouter = engine.Host("admin.example.com")
+	admin.Group("/user").Get("/:id", handler("admin"))
+	engine.Host(":tenant.example.com").Group("/user").Get("/:id", handler("tenant"))
+	if engine.Host("ADMIN.example.com") != admin {
+		t.Errorf("Host: the same host should return the same HostRouter")
+	}
+
+	tests := []struct {
+		host   string
+		status int
+		body   string
+	}{
+		{"api.other.com", http.StatusOK, "public  1"},
+		{"admin.example.com", http.StatusOK, "admin  1"},
+		{"ADMIN.example.com:8080", http.StatusOK, "admin  1"},
+		{"shop.example.com", http.StatusOK, "tenant shop 1"},
+		{"a.shop.example.com", http.StatusOK, "public  1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s: status = %d, body = %q, want %q", tt.host, w.Code, w.Body.String(), tt.body)
+		}
+	}
+
+	// Host 匹配后只在该 Host 的路由中查找
+	engine.Group("/index").Get("", handler("public"))
+	req := httptest.NewRequest(http.MethodGet, "/index", nil)
+	req.Host = "admin.example.com"
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("admin /index: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+type ctxKey string
+
+func TestEngine_WrapHTTP(t *testing.T) {
+	engine := New()
+	stdMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Std", "true")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey("user"), "tom")))
+		})
+	}
+	debug := engine.Group("/debug")
+	debug.Use(WrapMiddleware(stdMiddleware))
+	debug.Handle(http.MethodGet, "/static/*filepath", http.StripPrefix("/debug/static", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	})))
+	debug.Get("/user", WrapF(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Context().Value(ctxKey("user")))
+	}))
+	debug.Get("/ctx", func(ctx *Context) {
+		ctx.String(http.StatusOK, "%v", ctx.R.Context().Value(ctxKey("user")))
+	})
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/debug/static/css/app.css", "/css/app.css"},
+		{"/debug/user", "tom"},
+		{"/debug/ctx", "tom"},
+	}
+	for _, tt := range tests {
+		w := performRequest(engine, http.MethodGet, tt.path)
+		if w.Body.String() != tt.body || w.Header().Get("X-Std") != "true" {
+			t.Errorf("%s: body = %q, header = %v", tt.path, w.Body.String(), w.Header())
+		}
+	}
+}
+
+type docUser struct {
+	Name  string   `json:"name" msgo:"required"`
+	Age   int      `json:"age" validate:"required,min=18,max=50"`
+	Email string   `json:"email" validate:"email"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+func TestEngine_OpenAPI(t *testing.T) {
+	engine := New()
+	user := engine.Group("/user")
+	user.Get("/get/:id<int>", func(ctx *Context) {}).Named("user.get").
+		Doc(RouteDoc{Summary: "查询用户", Tags: []string{"user"}, Response: docUser{}})
+	user.Post("/add", func(ctx *Context) {}).
+		Doc(RouteDoc{Summary: "新增用户", Request: docUser{}, Response: R{}})
+	engine.ServeOpenAPI("/docs", openapi.Info{Title: "test", Version: "1.0.0"}, "/swagger-ui/")
+
+	w := performRequest(engine, http.MethodGet, "/docs/openapi.json")
+	var doc openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("openapi.json: %v, body = %s", err, w.Body.String())
+	}
+	if len(doc.Paths) != 2 {
+		t.Fatalf("paths = %v, want /user/get/{id} and /user/add", doc.Paths)
+	}
+
+	get := (*doc.Paths["/user/get/{id}"])["get"]
+	if get == nil || get.OperationID != "user.get" || get.Summary != "查询用户" {
+		t.Fatalf("get operation = %+v", get)
+	}
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || get.Parameters[0].Schema.Type != "integer" {
+		t.Errorf("get parameters = %+v", get.Parameters)
+	}
+	if ref := get.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/docUser" {
+		t.Errorf("get response ref = %q", ref)
+	}
+
+	add := (*doc.Paths["/user/add"])["post"]
+	if add == nil || add.RequestBody == nil {
+		t.Fatalf("post operation = %+v", add)
+	}
+
+	schema := doc.Components.Schemas["docUser"]
+	if schema == nil || strings.Join(schema.Required, ",") != "name,age" {
+		t.Fatalf("docUser schema = %+v", schema)
+	}
+	age := schema.Properties["age"]
+	if age.Type != "integer" || *age.Minimum != 18 || *age.Maximum != 50 {
+		t.Errorf("age schema = %+v", age)
+	}
+	if schema.Properties["email"].Format != "email" || schema.Properties["tags"].Items.Type != "string" {
+		t.Errorf("docUser properties = %+v", schema.Properties)
+	}
+	// R 中嵌入的 RError 展开为 code、msg
+	if r := doc.Components.Schemas["R"]; r == nil || r.Properties["code"] == nil || r.Properties["data"] == nil {
+		t.Errorf("R schema = %+v", r)
+	}
+
+	w = performRequest(engine, http.MethodGet, "/docs")
+	body := w.Body.String()
+	if !strings.Contains(body, `src="/swagger-ui/swagger-ui-bundle.js"`) || !strings.Contains(body, `href="/swagger-ui/swagger-ui.css"`) ||
+		!strings.Contains(body, `data-url="/docs/openapi.json"`) ||
+		!strings.Contains(body, `<script src="/docs/swagger-initializer.js">`) {
+		t.Errorf("swagger ui body = %s", body)
+	}
+	if w = performRequest(engine, http.MethodGet, "/docs/swagger-initializer.js"); !strings.Contains(w.Body.String(), "SwaggerUIBundle") {
+		t.Errorf("swagger initializer body = %s", w.Body.String())
+	}
+
+	// 没有设置 AssetsURL 时不默认从 CDN 加载
+	if err := openapi.WriteSwaggerUI(io.Discard, openapi.SwaggerUIConfig{}); err != openapi.ErrNoAssetsURL {
+		t.Errorf("WriteSwaggerUI without AssetsURL: err = %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("ServeOpenAPIWithConfig without AssetsURL should panic")
+		}
+	}()
+	engine.ServeOpenAPIWithConfig("/empty-docs", openapi.Info{Title: "test"}, openapi.SwaggerUIConfig{})
+}
+
+func TestRouterGroup_Replace_Remove(t *testing.T) {
+	engine := New()
+	plugin := engine.Group("/plugin")
+	plugin.Get("/status", func(ctx *Context) {
+		ctx.String(http.StatusOK, "v1")
+	}).Named("plugin.status")
+
+	// 替换、删除路由的同时处理请求
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					performRequest(engine, http.MethodGet, "/plugin/status")
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		plugin.Replace(http.MethodGet, "/status", func(ctx *Context) {
+			ctx.String(http.StatusOK, "v2")
+		})
+		plugin.Any(fmt.Sprintf("/extra/%d/:id", i), func(ctx *Context) {})
+	}
+	close(stop)
+	wg.Wait()
+
+	if w := performRequest(engine, http.MethodGet, "/plugin/status"); w.Body.String() != "v2" {
+		t.Errorf("replaced route body = %q, want v2", w.Body.String())
+	}
+	if _, err := engine.URLFor("plugin.status"); err == nil {
+		t.Errorf("replaced route should drop its name")
+	}
+	if n := len(engine.Routes()); n != 101 {
+		t.Errorf("len(Routes()) = %d, want 101", n)
+	}
+
+	if plugin.Remove(http.MethodPost, "/status") {
+		t.Errorf("Remove with another method should not remove the GET route")
+	}
+	if !plugin.Remove(http.MethodGet, "/status") || !plugin.Remove(ANY, "/extra/0/:id") {
+		t.Fatalf("Remove returned false for a registered route")
+	}
+	if w := performRequest(engine, http.MethodGet, "/plugin/status"); w.Code != http.StatusNotFound {
+		t.Errorf("removed route code = %d, want 404", w.Code)
+	}
+	if w := performRequest(engine, http.MethodPut, "/plugin/extra/0/1"); w.Code != http.StatusNotFound {
+		t.Errorf("removed ANY route code = %d, want 404", w.Code)
+	}
+	if w := performRequest(engine, http.MethodPut, "/plugin/extra/1/1"); w.Code != http.StatusOK {
+		t.Errorf("remaining ANY route code = %d, want 200", w.Code)
+	}
+
+	// 删除后可以重新注册参数名不同的路由
+	plugin.Get("/extra/0/:name", func(ctx *Context) {
+		ctx.String(http.StatusOK, ctx.Param("name"))
+	})
+	if w := performRequest(engine, http.MethodGet, "/plugin/extra/0/tom"); w.Body.String() != "tom" {
+		t.Errorf("re-registered route body = %q, want tom", w.Body.String())
+	}
+}
+
+func TestEngine_MiddlewareOrder(t *testing.T) {
+	engine := New()
+	var trace []string
+	mark := func(name string) MiddlewareFun {
+		return func(next Handler) Handler {
+			return func(ctx *Context) {
+				trace = append(trace, name)
+				next(ctx)
+			}
+		}
+	}
+
+	api := engine.Group("/api")
+	api.Use(mark("api1"), mark("api2"))
+	user := api.Group("/user")
+	user.Get("/info", func(ctx *Context) {
+		trace = append(trace, "handler")
+	}, mark("route1"), mark("route2"))
+	// 在分组、路由之后添加的中间件同样生效
+	user.Use(mark("user"))
+	engine.Use(mark("global1"), mark("global2"))
+	admin := engine.Host("admin.example.com")
+	admin.Group("/admin").Get("/info", func(ctx *Context) {
+		trace = append(trace, "handler")
+	})
+
+	tests := []struct {
+		host string
+		path string
+		want string
+	}{
+		{"example.com", "/api/user/info", "global1 global2 api1 api2 user route1 route2 handler"},
+		{"admin.example.com", "/admin/info", "global1 global2 handler"},
+		{"example.com", "/not/found", "global1 global2"},
+	}
+	for _, tt := range tests {
+		trace = nil
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+		if got := strings.Join(trace, " "); got != tt.want {
+			t.Errorf("%s%s: trace = %q, want %q", tt.host, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestEngine_Use_Concurrent(t *testing.T) {
+	engine := New()
+	user := engine.Group("/user")
+	user.Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "%d", len(ctx.W.Header().Values("X-Mark")))
+	})
+	mark := func(next Handler) Handler {
+		return func(ctx *Context) {
+			ctx.W.Header().Add("X-Mark", "1")
+			next(ctx)
+		}
+	}
+
+	// 添加中间件的同时处理请求，使用 go test -race 检查
+	var wg, started sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			performRequest(engine, http.MethodGet, "/user/info")
+			started.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					performRequest(engine, http.MethodGet, "/user/info")
+					performRequest(engine, http.MethodGet, "/not/found")
+				}
+			}
+		}()
+	}
+	started.Wait()
+	for i := 0; i < 50; i++ {
+		user.Use(mark)
+		engine.Use(mark)
+	}
+	close(stop)
+	wg.Wait()
+
+	if w := performRequest(engine, http.MethodGet, "/user/info"); w.Body.String() != "100" {
+		t.Errorf("middlewares = %s, want 100", w.Body.String())
+	}
+}
+
+func TestEngine_UseWithRule(t *testing.T) {
+	engine := New()
+	var trace []string
+	mark := func(name string) MiddlewareFun {
+		return func(next Handler) Handler {
+			return func(ctx *Context) {
+				trace = append(trace, name)
+				next(ctx)
+			}
+		}
+	}
+	engine.UseExcept(mark("log"), "/health", "/assets/**")
+	engine.UseWithRule(MiddlewareRule{Include: []string{"/api/*/info"}, Methods: []string{http.MethodPost}}, mark("audit"))
+	engine.Group("/").Get("health", func(ctx *Context) {})
+	engine.Group("/").Get("healthy", func(ctx *Context) {})
+	engine.Group("/").StaticFS("assets", http.Dir("."))
+	user := engine.Group("/api/user")
+	user.UseExcept(mark("auth"), "/login")
+	user.Get("/login", func(ctx *Context) {})
+	user.Get("/info", func(ctx *Context) {})
+	user.Post("/info", func(ctx *Context) {})
+	engine.Group("/api/any").Any("/info", func(ctx *Context) {})
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/health", ""},
+		{http.MethodGet, "/healthy", "log"},
+		{http.MethodGet, "/assets/engine.go", ""},
+		{http.MethodGet, "/api/user/login", "log"},
+		{http.MethodGet, "/api/user/info", "log auth"},
+		{http.MethodPost, "/api/user/info", "log audit auth"},
+		// ANY 路由按实际的请求方式匹配
+		{http.MethodGet, "/api/any/info", "log"},
+		{http.MethodPost, "/api/any/info", "log audit"},
+		{http.MethodPut, "/api/any/info", "log"},
+		// 只有 Exclude 规则的全局中间件对 NoRoute 生效
+		{http.MethodGet, "/not/found", "log"},
+	}
+	for _, tt := range tests {
+		trace = nil
+		performRequest(engine, tt.method, tt.path)
+		if got := strings.Join(trace, " "); got != tt.want {
+			t.Errorf("%s %s: trace = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestEngine_Mount(t *testing.T) {
+	var trace []string
+	mark := func(name string) MiddlewareFun {
+		return func(next Handler) Handler {
+			return func(ctx *Context) {
+				trace = append(trace, name)
+				next(ctx)
+			}
+		}
+	}
+
+	blog := New()
+	blog.Use(mark("blog"))
+	blog.SetRender(template.Must(template.New("post").Parse(`post {{.}}`)))
+	post := blog.Group("/post")
+	post.Get("/get/:id", func(ctx *Context) {
+		url, _ := blog.URLFor("post.get", "id", 2)
+		ctx.String(http.StatusOK, "%s %s %s", ctx.R.URL.Path, ctx.Param("id"), url)
+	}).Named("post.get")
+	post.Get("/html", func(ctx *Context) {
+		ctx.HtmlTemplate(http.StatusOK, "post", "html")
+	})
+	post.Get("/err", func(ctx *Context) {
+		ctx.ErrorHandler(fmt.Errorf("boom"))
+	})
+
+	engine := New()
+	engine.Use(mark("engine"))
+	engine.RegisterErrorHandler(func(err error) (int, any) {
+		return http.StatusTeapot, "engine: " + err.Error()
+	})
+	engine.Mount("/blog", blog)
+
+	trace = nil
+	w := performRequest(engine, http.MethodGet, "/blog/post/get/1")
+	if w.Body.String() != "/post/get/1 1 /blog/post/get/2" {
+		t.Errorf("mounted body = %q", w.Body.String())
+	}
+	if got := strings.Join(trace, " "); got != "engine blog" {
+		t.Errorf("mounted trace = %q, want %q", got, "engine blog")
+	}
+	if url, err := engine.URLFor("post.get", "id", 3); err != nil || url != "/blog/post/get/3" {
+		t.Errorf("engine.URLFor = %q, %v", url, err)
+	}
+	if w := performRequest(engine, http.MethodGet, "/blog/post/html"); w.Body.String() != "post html" {
+		t.Errorf("mounted template body = %q", w.Body.String())
+	}
+	if w := performRequest(engine, http.MethodGet, "/blog/post/err"); !strings.Contains(w.Body.String(), "engine: boom") {
+		t.Errorf("mounted error handler body = %q", w.Body.String())
+	}
+	if w := performRequest(engine, http.MethodGet, "/post/get/1"); w.Code != http.StatusNotFound {
+		t.Errorf("unmounted path code = %d, want 404", w.Code)
+	}
+
+	// 挂载后在子 engine 中注册、替换、删除的路由同步到当前 engine
+	blog.Group("/p").Get("/late/:x", func(ctx *Context) {
+		ctx.String(http.StatusOK, "late %s", ctx.Param("x"))
+	}).Named("post.late")
+	if w := performRequest(engine, http.MethodGet, "/blog/p/late/x"); w.Body.String() != "late x" {
+		t.Errorf("late route: code = %d, body = %q", w.Code, w.Body.String())
+	}
+	if url, err := engine.URLFor("post.late", "x", "y"); err != nil || url != "/blog/p/late/y" {
+		t.Errorf("late route engine.URLFor = %q, %v", url, err)
+	}
+	blog.Group("/p").Replace(http.MethodGet, "/late/:x", func(ctx *Context) {
+		ctx.String(http.StatusOK, "replaced %s", ctx.Param("x"))
+	})
+	if w := performRequest(engine, http.MethodGet, "/blog/p/late/x"); w.Body.String() != "replaced x" {
+		t.Errorf("replaced route: code = %d, body = %q", w.Code, w.Body.String())
+	}
+	blog.Group("/p").Remove(http.MethodGet, "/late/:x")
+	if w := performRequest(engine, http.MethodGet, "/blog/p/late/x"); w.Code != http.StatusNotFound {
+		t.Errorf("removed route code = %d, want 404", w.Code)
+	}
+
+	// 挂载的路由显示子 engine 中的处理函数名及全部中间件个数
+	for _, rt := range engine.Routes() {
+		if rt.Path != "/blog/post/get/:id" {
+			continue
+		}
+		if strings.Contains(rt.Handler, "mountedHandler") || !strings.Contains(rt.Handler, "TestEngine_Mount") || rt.Middlewares != 2 {
+			t.Errorf("mounted route info = %+v", rt)
+		}
+	}
+
+	// 循环挂载
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("mounting a parent engine should panic")
+			}
+		}()
+		blog.Mount("/engine", engine)
+	}()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("mounting an engine twice should panic")
+		}
+	}()
+	New().Mount("/other", blog)
+}
@@ -0,0 +1,108 @@
+package msgo
+
+import (
+	"net"
+	"strings"
+)
+
+// HostRouter 只匹配指定 Host 请求头的路由，由 Engine.Host 返回
+type HostRouter interface {
+	// Group 创建只处理该 Host 请求的分组
+	Group(groupName string) *routerGroup
+}
+
+var _ HostRouter = (*router)(nil)
+
+// Host 返回只匹配指定 Host 请求头的路由，其中的分组、路由只处理该 Host 的请求
+// 以 : 开头的部分匹配任意子域名，匹配的值通过 ctx.Param 获取
+//
+//	admin := engine.Host("admin.example.com")
+//	admin.Group("/user").Get("/list", handler)
+//
+//	tenant := engine.Host(":tenant.example.com")
+//	tenant.Group("/api").Get("/info", handler) // ctx.Param("tenant")
+//
+// 精确的 Host 优先于带参数的 Host；Host 匹配后只在该 Host 的路由中查找，没有匹配的 Host 时使用 engine 的路由
+func (e *Engine) Host(host string) HostRouter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	host = strings.ToLower(host)
+	hosts := e.hostRouters()
+	for _, r := range hosts {
+		if r.host == host {
+			return r
+		}
+	}
+	r := &router{engine: e, host: host, hostLabels: strings.Split(host, "."), hostParam: strings.Contains(host, ":")}
+	hosts = append(hosts[:len(hosts):len(hosts)], r)
+	e.hosts.Store(&hosts)
+	return r
+}
+
+// hostRouters 返回当前按 Host 匹配的路由
+func (e *Engine) hostRouters() []*router {
+	if hosts := e.hosts.Load(); hosts != nil {
+		return *hosts
+	}
+	return nil
+}
+
+// matchHost 返回 Host 请求头对应的路由，Host 中的参数追加到 ctx.params 中
+func (e *Engine) matchHost(ctx *Context) *router {
+	hosts := e.hostRouters()
+	if len(hosts) == 0 {
+		return e.router
+	}
+
+	host := ctx.R.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	// 精确匹配
+	for _, r := range hosts {
+		if !r.hostParam && strings.EqualFold(r.host, host) {
+			return r
+		}
+	}
+	// 带参数匹配
+	for _, r := range hosts {
+		if r.hostParam && r.matchHost(host, &ctx.params) {
+			return r
+		}
+	}
+	return e.router
+}
+
+// matchHost 按 . 分隔后逐级匹配，:name 匹配任意一级子域名
+func (r *router) matchHost(host string, params *Params) bool {
+	saved := len(*params)
+	for _, label := range r.hostLabels {
+		end := strings.IndexByte(host, '.')
+		if end < 0 {
+			end = len(host)
+		}
+		val := host[:end]
+		switch {
+		case label != "" && label[0] == ':':
+			if val == "" {
+				*params = (*params)[:saved]
+				return false
+			}
+			*params = append(*params, Param{Key: label[1:], Value: val})
+		case !strings.EqualFold(label, val):
+			*params = (*params)[:saved]
+			return false
+		}
+		if end == len(host) {
+			host = ""
+		} else {
+			host = host[end+1:]
+		}
+	}
+	if host != "" {
+		*params = (*params)[:saved]
+		return false
+	}
+	return true
+}
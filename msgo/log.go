@@ -117,7 +117,7 @@ func loggingWithConfig(config loggingConfig, nextFn Handler) Handler {
 		ip, _, _ := net.SplitHostPort(strings.TrimSpace(ctx.R.RemoteAddr))
 		params.ClientIP = net.ParseIP(ip)
 		params.Method = r.Method
-		params.StatusCode = ctx.StatusCode
+		params.StatusCode = ctx.Status()
 
 		if rawQuery != "" {
 			path += rawQuery
@@ -152,7 +152,7 @@ func Logging(next Handler) Handler {
 		ip, _, _ := net.SplitHostPort(strings.TrimSpace(ctx.R.RemoteAddr))
 		params.ClientIP = net.ParseIP(ip)
 		params.Method = r.Method
-		params.StatusCode = ctx.StatusCode
+		params.StatusCode = ctx.Status()
 
 		if rawQuery != "" {
 			path += rawQuery
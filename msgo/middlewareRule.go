@@ -0,0 +1,108 @@
+package msgo
+
+import (
+	"path"
+	"strings"
+)
+
+// MiddlewareRule 中间件的生效范围，在注册路由、添加中间件时按路由计算，请求时不再判断
+//
+// 路径规则为以 / 开头的路由前缀或通配规则，与注册的路由(而不是请求地址)比较:
+//
+//	/health       /health 及 /health/ 下的全部路由
+//	/assets/**    ** 匹配剩余的任意级路径
+//	/user/*/info  * 匹配单级路径，同 path.Match 可匹配部分路径，如 /docs/*.json
+type MiddlewareRule struct {
+	Include []string // 生效的路由，为空时对全部路由生效
+	Exclude []string // 不生效的路由，优先于 Include
+	Methods []string // 生效的请求方式，为空时对全部请求方式生效；ANY 注册的路由按实际的请求方式匹配
+}
+
+// middleware 添加的中间件及其生效范围，rule 为 nil 时对全部路由生效
+type middleware struct {
+	fn   MiddlewareFun
+	rule *MiddlewareRule
+}
+
+func newMiddlewares(rule *MiddlewareRule, fns []MiddlewareFun) []middleware {
+	middlewares := make([]middleware, 0, len(fns))
+	for _, fn := range fns {
+		middlewares = append(middlewares, middleware{fn: fn, rule: rule})
+	}
+	return middlewares
+}
+
+// match 中间件是否对请求方式为 method、完整路由为 path 的路由生效
+func (m middleware) match(method, path string) bool {
+	return m.rule == nil || m.rule.match(method, path)
+}
+
+// global 中间件是否对 NoRoute、NoMethod、OPTIONS 的处理函数生效，只有 Exclude 规则时生效
+func (m middleware) global() bool {
+	return m.rule == nil || len(m.rule.Include) == 0 && len(m.rule.Methods) == 0
+}
+
+func (r *MiddlewareRule) match(method, path string) bool {
+	if len(r.Methods) > 0 && !containsFold(r.Methods, method) {
+		return false
+	}
+	if len(r.Include) > 0 && !matchAnyPath(r.Include, path) {
+		return false
+	}
+	return !matchAnyPath(r.Exclude, path)
+}
+
+// withPrefix 返回路径规则加上分组前缀后的规则
+func (r MiddlewareRule) withPrefix(prefix string) *MiddlewareRule {
+	join := func(patterns []string) []string {
+		rs := make([]string, 0, len(patterns))
+		for _, p := range patterns {
+			rs = append(rs, prefix+p)
+		}
+		return rs
+	}
+	r.Include = join(r.Include)
+	r.Exclude = join(r.Exclude)
+	return &r
+}
+
+func containsFold(arr []string, s string) bool {
+	for _, v := range arr {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyPath(patterns []string, route string) bool {
+	for _, p := range patterns {
+		if matchPath(p, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath 路由 route 是否匹配路径规则 pattern
+func matchPath(pattern, route string) bool {
+	if !strings.Contains(pattern, "*") {
+		prefix := strings.TrimSuffix(pattern, "/")
+		return route == pattern || route == prefix || strings.HasPrefix(route, prefix+"/")
+	}
+
+	patterns := strings.Split(strings.Trim(pattern, "/"), "/")
+	routes := strings.Split(strings.Trim(route, "/"), "/")
+	for i, p := range patterns {
+		if p == "**" {
+			return true
+		}
+		if i >= len(routes) {
+			return false
+		}
+		if ok, err := path.Match(p, routes[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return len(patterns) == len(routes)
+}
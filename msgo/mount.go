@@ -0,0 +1,127 @@
+package msgo
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// Mount 将子 engine 的路由挂载到 prefix 下，prefix 以 / 开头
+//
+//		blog := msgo.New()
+//		blog.Group("/post").Get("/get/:id", handler) // 挂载后为 /blog/post/get/:id
+//		engine.Mount("/blog", blog)
+//
+//	  - 请求先经过当前 engine 的全局中间件，再经过子 engine 的全局、分组级、方法级中间件
+//	  - 子 engine 的中间件及处理函数中，请求地址去掉了 prefix，使用子 engine 的模板(没有加载模板时使用当前 engine 的模板)
+//	  - 子 engine 使用当前 engine 的日志及错误处理函数
+//	  - 命名路由同时注册到当前 engine，子 engine 的 URLFor 生成的地址带 prefix
+//	  - 挂载后在子 engine 中注册、替换、删除的路由及设置的名称、文档同步到当前 engine
+//
+// 不挂载子 engine 中按 Host 匹配的路由；一个子 engine 只能挂载一次
+func (e *Engine) Mount(prefix string, sub *Engine) *Engine {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" || prefix[0] != '/' {
+		panic("挂载的路径 " + prefix + " 必须以 / 开头且不能为 /")
+	}
+	// 子 engine 是当前 engine 或其上级 engine 时形成循环挂载
+	for p := e; p != nil; {
+		if p == sub {
+			panic("engine 不能挂载到自身或上级 engine")
+		}
+		p.mu.RLock()
+		next := p.parent
+		p.mu.RUnlock()
+		p = next
+	}
+	group := e.Group(prefix)
+
+	// 锁的顺序为先子 engine 后上级 engine，与子 engine 中注册路由时同步到上级 engine 的顺序一致
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.parent != nil {
+		panic("engine 已挂载到 " + sub.mountPath())
+	}
+	sub.parent = e
+	sub.prefix = prefix
+	sub.mountGroup = group
+	sub.logger = e.logger
+	for _, rt := range sub.router.routes {
+		sub.mountRoute(rt, false)
+	}
+	return e
+}
+
+// mountRoute 将子 engine 的路由注册到上级 engine 中挂载的分组，调用时持有子 engine 的锁
+func (e *Engine) mountRoute(rt *route, replace bool) {
+	p := e.parent
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rt.mounted = e.mountGroup.registerLocked(rt.method, rt.path, e.mountedHandler(rt), nil, replace)
+	rt.mounted.source = rt
+	if rt.name != "" {
+		p.nameRoute(rt.mounted, rt.name)
+	}
+	if rt.doc != nil {
+		p.setDoc(rt.mounted, rt.doc)
+	}
+}
+
+// mountedHandler 挂载到上级 engine 的路由的处理函数，去掉地址中的 prefix 后执行子 engine 的处理函数链
+func (e *Engine) mountedHandler(rt *route) Handler {
+	return func(ctx *Context) {
+		engine, r := ctx.e, ctx.R
+		handlers, index := ctx.handlers, ctx.index
+		defer func() {
+			aborted := ctx.IsAborted()
+			ctx.e, ctx.R = engine, r
+			ctx.handlers, ctx.index = handlers, index
+			if aborted {
+				ctx.Abort()
+			}
+		}()
+
+		ctx.e, ctx.R = e, stripPrefix(r, e.prefix)
+		ctx.run(rt.chain(r.Method))
+	}
+}
+
+// stripPrefix 返回去掉了地址中的 prefix 的请求
+func stripPrefix(r *http.Request, prefix string) *http.Request {
+	req := new(http.Request)
+	*req = *r
+	u := *r.URL
+	u.Path = strings.TrimPrefix(r.URL.Path, prefix)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if u.RawPath != "" {
+		u.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+	}
+	req.URL = &u
+	return req
+}
+
+// mountPath 挂载到上级 engine 的完整路径，没有挂载时为空
+func (e *Engine) mountPath() string {
+	if e.parent == nil {
+		return ""
+	}
+	return e.parent.mountPath() + e.prefix
+}
+
+// errorHandler 返回错误处理函数，挂载的子 engine 使用上级 engine 的错误处理函数
+func (e *Engine) errorHandler() ErrorHandlerFun {
+	if e.parent != nil {
+		return e.parent.errorHandler()
+	}
+	return e.errHandler
+}
+
+// template 返回模板，挂载的子 engine 没有加载模板时使用上级 engine 的模板
+func (e *Engine) template() *template.Template {
+	if e.render.Template == nil && e.parent != nil {
+		return e.parent.template()
+	}
+	return e.render.Template
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"github.com/BurntSushi/toml"
 	"os"
+	"strings"
 )
 
 type MsConf struct {
@@ -13,28 +14,69 @@ type MsConf struct {
 	Redis    map[string]any
 	Template map[string]any
 	Pool     map[string]any
+	Fs       map[string]any
 }
 
 var Conf = &MsConf{}
 
+// 模板、静态文件的来源
+const (
+	FsDisk  = "disk"  // 从磁盘读取，用于开发环境
+	FsEmbed = "embed" // 使用 embed 编译进程序中的文件，用于单文件部署
+)
+
+// FsSource 模板、静态文件的来源，由 [fs] source 配置，默认为 disk
+func (c *MsConf) FsSource() string {
+	if source, ok := c.Fs["source"].(string); ok && source == FsEmbed {
+		return FsEmbed
+	}
+	return FsDisk
+}
+
+// 默认的配置文件路径
+const defaultConfPath = "conf/conf.toml"
+
 func init() {
-	loadToml(Conf)
+	// -conf 注册到 flag.CommandLine，应用调用 flag.Parse 时可以识别该参数
+	// 这里不调用 flag.Parse，避免在应用(及 go test)注册自己的参数之前解析命令行
+	flag.String("conf", defaultConfPath, "app config file")
+	loadToml(Conf, confPath(os.Args[1:]))
 }
 
-func loadToml(conf *MsConf) {
+// confPath 从命令行参数中取 -conf 的值，支持 -conf path、-conf=path 及 --conf 的写法，没有时使用默认路径
+func confPath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if name == "conf" {
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+			return ""
+		}
+		if strings.HasPrefix(name, "conf=") {
+			return strings.TrimPrefix(name, "conf=")
+		}
+	}
+	return defaultConfPath
+}
 
-	confPara := flag.String("conf", "conf/conf.toml", "app config file")
-	flag.Parse()
-	if *confPara == "" {
+func loadToml(conf *MsConf, confPara string) {
+	if confPara == "" {
 		panic(errors.New("conf para is empty"))
 	}
 
-	_, err := os.Stat(*confPara)
+	_, err := os.Stat(confPara)
 	if err != nil {
 		panic(errors.New("conf.toml file is not exists"))
 	}
 
-	_, err = toml.DecodeFile(*confPara, conf)
+	_, err = toml.DecodeFile(confPara, conf)
 	if err != nil {
 		panic(errors.New("conf.toml file parse error"))
 	}
@@ -0,0 +1,248 @@
+package msgo
+
+import (
+	"fmt"
+	"github.com/kk88183080k/goWeb/msgo/openapi"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RouteDoc 路由的文档信息，用于生成 OpenAPI 文档
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Request     any  // 请求参数的结构体，GET、HEAD、DELETE 请求作为查询参数，其他请求作为 json 请求体
+	Response    any  // 200 响应的结构体
+	Hidden      bool // 不出现在文档中
+}
+
+// Doc 为路由设置文档信息，路由已被删除或替换时 panic
+//
+//	group.Post("/add", handler).Doc(msgo.RouteDoc{Summary: "新增用户", Tags: []string{"user"}, Request: User{}, Response: msgo.R{}})
+func (h *RouteHandle) Doc(doc RouteDoc) *RouteHandle {
+	e := h.router.engine
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if h.route.removed {
+		panic("文档 " + doc.Summary + " 对应的路由 " + h.route.method + " " + h.route.path + " 已被删除")
+	}
+	e.setDoc(h.route, &doc)
+	return h
+}
+
+// setDoc 设置路由的文档信息，挂载的子 engine 同时设置上级 engine 中对应的路由，调用时持有 engine 的锁
+func (e *Engine) setDoc(rt *route, doc *RouteDoc) {
+	rt.doc = doc
+	if rt.mounted != nil {
+		p := e.parent
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.setDoc(rt.mounted, doc)
+	}
+}
+
+// OpenAPI 由已注册的路由生成 OpenAPI 3 文档
+// 请求、响应结构体按 json、validate、msgo:"required" 标签生成 Schema
+// 路由中的参数转换为路径参数，参数约束转换为对应的类型
+func (e *Engine) OpenAPI(info openapi.Info) *openapi.Document {
+	g := openapi.NewGenerator()
+	doc := &openapi.Document{OpenAPI: openapi.Version, Info: info, Paths: map[string]*openapi.PathItem{}}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, r := range append([]*router{e.router}, e.hostRouters()...) {
+		for _, rt := range r.routes {
+			if rt.doc != nil && rt.doc.Hidden {
+				continue
+			}
+			path, params := openAPIPath(rt.path)
+			item, ok := doc.Paths[path]
+			if !ok {
+				item = &openapi.PathItem{}
+				doc.Paths[path] = item
+			}
+
+			methods := []string{rt.method}
+			if rt.method == ANY {
+				methods = openAPIAnyMethods
+			}
+			for _, method := range methods {
+				key := strings.ToLower(method)
+				// 多个 Host 下的相同路由只保留第一个
+				if _, exists := (*item)[key]; exists {
+					continue
+				}
+				(*item)[key] = rt.operation(g, method, params)
+			}
+		}
+	}
+	doc.Components = g.Components()
+	return doc
+}
+
+// openAPIAnyMethods ANY 路由在文档中对应的请求方式，OpenAPI 不支持 CONNECT
+var openAPIAnyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodHead,
+	http.MethodOptions, http.MethodDelete, http.MethodTrace,
+}
+
+func (rt *route) operation(g *openapi.Generator, method string, params []*openapi.Parameter) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: rt.name,
+		Parameters:  params,
+		Responses:   map[string]*openapi.Response{"200": {Description: http.StatusText(http.StatusOK)}},
+	}
+	if rt.method == ANY && rt.name != "" {
+		op.OperationID = rt.name + "." + strings.ToLower(method)
+	}
+	if rt.doc == nil {
+		return op
+	}
+
+	op.Summary = rt.doc.Summary
+	op.Description = rt.doc.Description
+	op.Tags = rt.doc.Tags
+	if rt.doc.Request != nil {
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodDelete:
+			op.Parameters = append(op.Parameters, g.ParametersOf(rt.doc.Request, "query")...)
+		default:
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]*openapi.MediaType{"application/json": {Schema: g.SchemaOf(rt.doc.Request)}},
+			}
+		}
+	}
+	if rt.doc.Response != nil {
+		op.Responses["200"].Content = map[string]*openapi.MediaType{"application/json": {Schema: g.SchemaOf(rt.doc.Response)}}
+	}
+	return op
+}
+
+// openAPIPath 将路由转换为 OpenAPI 的路径，返回路径及路径参数
+//
+//	/user/get/:id<int>        -> /user/get/{id}      id 为 integer
+//	/user/update/{id:[0-9]+}  -> /user/update/{id}   id 为 pattern 为 ^(?:[0-9]+)$ 的 string
+//	/assets/*filepath         -> /assets/{filepath}
+func openAPIPath(path string) (string, []*openapi.Parameter) {
+	var sb strings.Builder
+	var params []*openapi.Parameter
+	for path != "" {
+		i := nextWildcard(path)
+		sb.WriteString(path[:i])
+		path = path[i:]
+		if path == "" {
+			break
+		}
+
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		seg := path[:end]
+		path = path[end:]
+
+		var name, expr string
+		if isCatchAll(seg) {
+			name = catchAllKey(seg)
+			if name == "**" {
+				name = "wildcard"
+			}
+		} else {
+			name, expr = parseParamSegment(seg)
+		}
+		if name == "" {
+			// 没有参数名的 * 按出现的位置命名
+			name = "param" + strconv.Itoa(len(params)+1)
+		}
+		fmt.Fprintf(&sb, "{%s}", name)
+		params = append(params, &openapi.Parameter{Name: name, In: "path", Required: true, Schema: constraintSchema(expr)})
+	}
+	return sb.String(), params
+}
+
+// constraintSchema 参数约束对应的 Schema
+func constraintSchema(expr string) *openapi.Schema {
+	switch expr {
+	case "":
+		return &openapi.Schema{Type: "string"}
+	case "int":
+		return &openapi.Schema{Type: "integer", Format: "int64"}
+	case "uint":
+		min := float64(0)
+		return &openapi.Schema{Type: "integer", Format: "int64", Minimum: &min}
+	case "float":
+		return &openapi.Schema{Type: "number", Format: "double"}
+	case "bool":
+		return &openapi.Schema{Type: "boolean"}
+	default:
+		return &openapi.Schema{Type: "string", Pattern: "^(?:" + expr + ")$"}
+	}
+}
+
+// OpenAPIHandler 以 json 返回 OpenAPI 文档，每次请求时按当前注册的路由生成
+func (e *Engine) OpenAPIHandler(info openapi.Info) Handler {
+	return func(ctx *Context) {
+		ctx.JSON(http.StatusOK, e.OpenAPI(info))
+	}
+}
+
+// SwaggerUIHandler 返回 Swagger UI 页面，页面从 conf.SpecURL 加载文档，conf.AssetsURL 为空时 panic
+func SwaggerUIHandler(conf openapi.SwaggerUIConfig) Handler {
+	if conf.AssetsURL == "" {
+		panic(openapi.ErrNoAssetsURL)
+	}
+	return func(ctx *Context) {
+		ctx.W.Header().Set("Content-Type", "text/html; charset=utf-8")
+		ctx.W.WriteHeader(http.StatusOK)
+		if err := openapi.WriteSwaggerUI(ctx.W, conf); err != nil {
+			ctx.Logger.Error(err)
+		}
+	}
+}
+
+// SwaggerInitializerHandler 返回 Swagger UI 的初始化脚本，对应 SwaggerUIConfig.InitializerURL
+func SwaggerInitializerHandler(ctx *Context) {
+	ctx.W.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	ctx.W.WriteHeader(http.StatusOK)
+	if err := openapi.WriteSwaggerInitializer(ctx.W); err != nil {
+		ctx.Logger.Error(err)
+	}
+}
+
+// ServeOpenAPI 在 path 下提供 Swagger UI 页面，及 path/openapi.json 文档，这些路由不出现在文档中
+// Swagger UI 的静态文件(swagger-ui-dist)从 assetsURL 加载，见 openapi.SwaggerUIConfig.AssetsURL
+//
+//	//go:embed swagger-ui-dist
+//	var swaggerFS embed.FS
+//
+//	engine.Group("/").StaticByConf("swagger-ui", "swagger-ui-dist", swaggerFS)
+//	engine.ServeOpenAPI("/docs", openapi.Info{Title: "blog", Version: "1.0.0"}, "/swagger-ui")
+//	GET /docs                         Swagger UI
+//	GET /docs/openapi.json            OpenAPI 文档
+//	GET /docs/swagger-initializer.js  Swagger UI 的初始化脚本
+func (e *Engine) ServeOpenAPI(path string, info openapi.Info, assetsURL string) *routerGroup {
+	return e.ServeOpenAPIWithConfig(path, info, openapi.SwaggerUIConfig{AssetsURL: assetsURL})
+}
+
+// ServeOpenAPIWithConfig 同 ServeOpenAPI，按 ui 配置 Swagger UI 页面，ui.AssetsURL 必填，
+// ui 中为空的 Title、SpecURL、InitializerURL 使用默认值
+func (e *Engine) ServeOpenAPIWithConfig(path string, info openapi.Info, ui openapi.SwaggerUIConfig) *routerGroup {
+	path = strings.TrimSuffix(path, "/")
+	if ui.Title == "" {
+		ui.Title = info.Title
+	}
+	if ui.SpecURL == "" {
+		ui.SpecURL = path + "/openapi.json"
+	}
+	if ui.InitializerURL == "" {
+		ui.InitializerURL = path + "/swagger-initializer.js"
+	}
+	group := e.Group(path)
+	group.Get("", SwaggerUIHandler(ui)).Doc(RouteDoc{Hidden: true})
+	group.Get("/openapi.json", e.OpenAPIHandler(info)).Doc(RouteDoc{Hidden: true})
+	group.Get("/swagger-initializer.js", SwaggerInitializerHandler).Doc(RouteDoc{Hidden: true})
+	return group
+}
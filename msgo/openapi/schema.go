@@ -0,0 +1,294 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generator 按结构体的 json、validate、msgo:"required" 标签生成 Schema
+// 命名的结构体定义在 Components 中，通过 $ref 引用
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func NewGenerator() *Generator {
+	return &Generator{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// Components 返回生成过程中定义的结构体，没有时返回 nil
+func (g *Generator) Components() *Components {
+	if len(g.schemas) == 0 {
+		return nil
+	}
+	return &Components{Schemas: g.schemas}
+}
+
+// SchemaOf 返回 v 的类型对应的 Schema，v 为 nil 时返回空 Schema(任意类型)
+func (g *Generator) SchemaOf(v any) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+// ParametersOf 将结构体 v 的字段转换为参数，in 为 query 或 path
+// 参数名依次取 query、form、json 标签，没有时使用字段名
+func (g *Generator) ParametersOf(v any, in string) []*Parameter {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []*Parameter
+	for _, field := range fields(t) {
+		name, ok := fieldName(field, "query", "form", "json")
+		if !ok {
+			continue
+		}
+		schema := g.schema(field.Type)
+		required := applyValidate(schema, field)
+		params = append(params, &Parameter{Name: name, In: in, Required: required || in == "path", Schema: schema})
+	}
+	return params
+}
+
+func (g *Generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: float(0)}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64", Minimum: float(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte 按 json 序列化为 base64 字符串
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.ref(t)
+	default:
+		// interface 等任意类型
+		return &Schema{}
+	}
+}
+
+// ref 命名的结构体定义在 Components 中，返回引用
+func (g *Generator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.uniqueName(t)
+		g.names[t] = name
+		// 先占位，避免结构体自引用时无限递归
+		g.schemas[name] = &Schema{}
+		g.schemas[name] = g.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// uniqueName 结构体在 Components 中的名称，不同包中的同名结构体加上包名区分
+func (g *Generator) uniqueName(t reflect.Type) string {
+	name := sanitizeName(t.Name())
+	if _, exists := g.schemas[name]; !exists {
+		return name
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	name = sanitizeName(pkg + "." + t.Name())
+	for i := 2; ; i++ {
+		if _, exists := g.schemas[name]; !exists {
+			return name
+		}
+		name = sanitizeName(pkg+"."+t.Name()) + strconv.Itoa(i)
+	}
+}
+
+// sanitizeName Components 中的名称只能包含字母、数字及 . - _
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range fields(t) {
+		name, ok := fieldName(field, "json")
+		if !ok {
+			continue
+		}
+		prop := g.schema(field.Type)
+		if hasTagOption(field.Tag.Get("json"), "string") {
+			// json:",string" 的数值、布尔值按字符串序列化
+			prop = &Schema{Type: "string"}
+		}
+		if applyValidate(prop, field) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
+
+// fields 返回结构体导出的字段，匿名嵌入且没有 json 名称的结构体展开为其字段
+func fields(t reflect.Type) []reflect.StructField {
+	var rs []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			ft := field.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if ft.Kind() == reflect.Struct && name == "" {
+				rs = append(rs, fields(ft)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		rs = append(rs, field)
+	}
+	return rs
+}
+
+// fieldName 按 tags 的顺序取字段的名称，标签为 - 时忽略该字段
+func fieldName(field reflect.StructField, tags ...string) (string, bool) {
+	for _, tag := range tags {
+		val, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		name := strings.Split(val, ",")[0]
+		if name == "-" {
+			return "", false
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+	return field.Name, true
+}
+
+func hasTagOption(tag, option string) bool {
+	opts := strings.Split(tag, ",")
+	for _, opt := range opts[1:] {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// applyValidate 将 validate 标签中的规则设置到 s 上，返回字段是否必填
+// 支持 required、min、max、len、gte、lte、oneof、email、url、uuid，msgo:"required" 同样表示必填
+func applyValidate(s *Schema, field reflect.StructField) bool {
+	required := field.Tag.Get("msgo") == "required"
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		key, val, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+		case "min", "gte":
+			setBound(s, val, true)
+		case "max", "lte":
+			setBound(s, val, false)
+		case "len":
+			setBound(s, val, true)
+			setBound(s, val, false)
+		case "oneof":
+			for _, v := range strings.Fields(val) {
+				s.Enum = append(s.Enum, enumValue(s.Type, v))
+			}
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "uuid":
+			s.Format = "uuid"
+		}
+	}
+	return required
+}
+
+// setBound 按类型设置最小(min=true)或最大值、长度、元素个数
+func setBound(s *Schema, val string, min bool) {
+	n, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return
+	}
+	switch s.Type {
+	case "integer", "number":
+		if min {
+			s.Minimum = &n
+		} else {
+			s.Maximum = &n
+		}
+	case "string":
+		l := int(n)
+		if min {
+			s.MinLength = &l
+		} else {
+			s.MaxLength = &l
+		}
+	case "array":
+		l := int(n)
+		if min {
+			s.MinItems = &l
+		} else {
+			s.MaxItems = &l
+		}
+	}
+}
+
+func enumValue(typ, val string) any {
+	switch typ {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(val, 64); err == nil {
+			return n
+		}
+	}
+	return val
+}
+
+func float(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,8 @@
+window.onload = function () {
+    var dom = document.getElementById("swagger-ui");
+    window.ui = SwaggerUIBundle({
+        url: dom.getAttribute("data-url"),
+        dom_id: "#swagger-ui",
+        deepLinking: true
+    });
+};
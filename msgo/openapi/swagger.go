@@ -0,0 +1,51 @@
+package openapi
+
+import (
+	_ "embed"
+	"errors"
+	"html/template"
+	"io"
+	"strings"
+)
+
+//go:embed swagger.html
+var swaggerHTML string
+
+//go:embed swagger-initializer.js
+var swaggerInitializer string
+
+var swaggerTemplate = template.Must(template.New("swagger").Parse(swaggerHTML))
+
+// SwaggerUIConfig Swagger UI 页面的配置
+type SwaggerUIConfig struct {
+	Title   string
+	SpecURL string // 文档的地址
+	// AssetsURL swagger-ui-dist 中 swagger-ui.css、swagger-ui-bundle.js 所在的地址，必填
+	// 建议将 swagger-ui-dist 作为静态文件挂载，并设置为挂载的地址；
+	// 使用 CDN(如 https://unpkg.com/swagger-ui-dist@5)时页面加载的脚本没有完整性校验
+	AssetsURL string
+	// InitializerURL 初始化脚本的地址，脚本内容由 WriteSwaggerInitializer 输出
+	// 为空时脚本内联在页面中；启用 CSP 不允许内联脚本时需要设置
+	InitializerURL string
+}
+
+// ErrNoAssetsURL 没有设置 SwaggerUIConfig.AssetsURL
+var ErrNoAssetsURL = errors.New("openapi: SwaggerUIConfig.AssetsURL 不能为空")
+
+// WriteSwaggerUI 输出 Swagger UI 页面，页面从 conf.SpecURL 加载文档，从 conf.AssetsURL 加载静态文件
+func WriteSwaggerUI(w io.Writer, conf SwaggerUIConfig) error {
+	if conf.AssetsURL == "" {
+		return ErrNoAssetsURL
+	}
+	conf.AssetsURL = strings.TrimSuffix(conf.AssetsURL, "/")
+	return swaggerTemplate.Execute(w, struct {
+		SwaggerUIConfig
+		Initializer template.JS
+	}{conf, template.JS(swaggerInitializer)})
+}
+
+// WriteSwaggerInitializer 输出 Swagger UI 的初始化脚本
+func WriteSwaggerInitializer(w io.Writer) error {
+	_, err := io.WriteString(w, swaggerInitializer)
+	return err
+}
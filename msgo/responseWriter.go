@@ -0,0 +1,117 @@
+package msgo
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"net/http"
+)
+
+// noWritten 响应头还没有写入时 size 的值
+const noWritten = -1
+
+// ResponseWriter ctx.W 的默认实现，记录实际写入的状态码及响应体大小
+//
+// WriteHeader 只记录状态码，第一次写入响应体(或 Flush、请求结束)时才写入响应头，
+// 因此 WriteHeader 之后仍可设置响应头；响应头写入后再调用 WriteHeader 会被忽略
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+
+	Status() int     // 响应的状态码
+	Size() int       // 已写入的响应体字节数，响应头还没有写入时为 -1
+	Written() bool   // 响应头是否已写入
+	WriteHeaderNow() // 立即写入响应头
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int
+	discard bool // 丢弃响应体，用于 GET 路由处理 HEAD 请求
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = noWritten
+	w.discard = false
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code <= 0 || w.status == code {
+		return
+	}
+	if w.Written() {
+		log.Printf("[WARNING] 响应头已写入，忽略状态码 %d，当前状态码为 %d\n", code, w.status)
+		return
+	}
+	w.status = code
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (n int, err error) {
+	w.WriteHeaderNow()
+	if w.discard {
+		w.size += len(data)
+		return len(data), nil
+	}
+	n, err = w.ResponseWriter.Write(data)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+// Flush 写入响应头后发送已缓冲的数据，底层不支持时只写入响应头
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack 接管连接，之后不再写入响应头
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return hijacker.Hijack()
+}
+
+// Push HTTP/2 服务端推送，底层不支持时返回 http.ErrNotSupported
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap 返回原始的 http.ResponseWriter，用于 http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
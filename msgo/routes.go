@@ -0,0 +1,81 @@
+package msgo
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo 已注册路由的信息
+type RouteInfo struct {
+	Method      string `json:"method"`         // 请求方式，ANY 表示全部
+	Host        string `json:"host,omitempty"` // 路由匹配的 Host，为空时匹配全部
+	Path        string `json:"path"`           // 完整路由
+	Handler     string `json:"handler"`        // 业务处理函数名
+	Middlewares int    `json:"middlewares"`    // 对该路由生效的全局、分组级(包括上级分组)、方法级中间件的个数，ANY 路由为 GET 请求的个数
+	Name        string `json:"name,omitempty"`
+}
+
+// Routes 按注册顺序返回全部已注册的路由，按 Host 匹配的路由在后
+func (e *Engine) Routes() []RouteInfo {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	routes := make([]RouteInfo, 0, len(e.routes))
+	for _, r := range append([]*router{e.router}, e.hostRouters()...) {
+		for _, rt := range r.routes {
+			routes = append(routes, rt.info())
+		}
+	}
+	return routes
+}
+
+// info 返回路由信息，挂载的路由返回子 engine 中路由的处理函数名，中间件个数包括子 engine 中的中间件
+func (rt *route) info() RouteInfo {
+	info := RouteInfo{
+		Method:      rt.method,
+		Host:        rt.group.router.host,
+		Path:        rt.path,
+		Handler:     nameOfFunction(rt.handler),
+		Middlewares: len(rt.chain(rt.methods()[0])) - 1,
+		Name:        rt.name,
+	}
+	if rt.source != nil {
+		source := rt.source.info()
+		info.Handler = source.Handler
+		info.Middlewares += source.Middlewares
+	}
+	return info
+}
+
+func nameOfFunction(f any) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// RoutesHandler 以 json 返回全部已注册路由的处理函数，用于注册调试接口
+//
+//	engine.Group("/debug").Get("/routes", engine.RoutesHandler())
+func (e *Engine) RoutesHandler() Handler {
+	return func(ctx *Context) {
+		ctx.JSON(http.StatusOK, e.Routes())
+	}
+}
+
+// printRoutes 调试模式(Engine.Debug)时打印路由表
+func (e *Engine) printRoutes() {
+	if !e.Debug {
+		return
+	}
+
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER\tMIDDLEWARES\tNAME")
+	for _, rt := range e.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", rt.Method, rt.Host+rt.Path, rt.Handler, rt.Middlewares, rt.Name)
+	}
+	tw.Flush()
+	e.logger.Info("registered routes:\n" + sb.String())
+}
@@ -0,0 +1,216 @@
+package msgo
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/kk88183080k/goWeb/msgo/msconf"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultIndexFile 目录的默认首页
+const defaultIndexFile = "index.html"
+
+// StaticConfig 静态文件服务的配置
+type StaticConfig struct {
+	Index  string // 目录的首页文件，默认为 index.html
+	Browse bool   // 目录没有首页文件时是否列出目录中的文件，默认不列出
+	SPA    bool   // 单页应用模式，文件不存在时返回根目录的首页文件
+}
+
+// Static 将 root 目录挂载到分组的 prefix 下
+//
+//	engine.Group("/").Static("assets", "./public")
+//	GET /assets/css/index.css -> ./public/css/index.css
+func (rg *routerGroup) Static(prefix, root string) *routerGroup {
+	return rg.StaticFS(prefix, http.Dir(root))
+}
+
+// StaticFS 将文件系统 fileSystem 挂载到分组的 prefix 下
+func (rg *routerGroup) StaticFS(prefix string, fileSystem http.FileSystem) *routerGroup {
+	return rg.StaticFSWithConfig(prefix, fileSystem, StaticConfig{})
+}
+
+// StaticFromFS 将文件系统 fsys(如 embed.FS) 挂载到分组的 prefix 下
+func (rg *routerGroup) StaticFromFS(prefix string, fsys fs.FS) *routerGroup {
+	return rg.StaticFS(prefix, http.FS(fsys))
+}
+
+// StaticByConf 按配置挂载静态文件，[fs] source 为 embed 时使用 fsys 中的 root 目录，否则使用磁盘上的 root 目录
+//
+//	//go:embed public
+//	var publicFS embed.FS
+//
+//	engine.Group("/").StaticByConf("assets", "public", publicFS)
+func (rg *routerGroup) StaticByConf(prefix, root string, fsys fs.FS) *routerGroup {
+	if msconf.Conf.FsSource() != msconf.FsEmbed {
+		return rg.Static(prefix, root)
+	}
+	sub, err := fs.Sub(fsys, path.Clean(root))
+	if err != nil {
+		panic(err)
+	}
+	return rg.StaticFromFS(prefix, sub)
+}
+
+// StaticFSWithConfig 按配置将文件系统 fileSystem 挂载到分组的 prefix 下
+// 支持 Last-Modified、ETag 及 Range 请求
+func (rg *routerGroup) StaticFSWithConfig(prefix string, fileSystem http.FileSystem, conf StaticConfig) *routerGroup {
+	if conf.Index == "" {
+		conf.Index = defaultIndexFile
+	}
+	api := strings.TrimSuffix(prefix, "/") + "/*filepath"
+	s := &staticServer{fileSystem: fileSystem, conf: conf}
+	rg.Get(api, s.serve)
+	rg.Head(api, s.serve)
+	return rg
+}
+
+// staticServer 一个挂载点的静态文件服务
+type staticServer struct {
+	fileSystem http.FileSystem
+	conf       StaticConfig
+	etags      sync.Map // 文件名 -> *contentETag，修改时间为零的文件按内容计算的 ETag
+}
+
+// contentETag 按文件内容计算的 ETag，文件大小变化时重新计算
+type contentETag struct {
+	size int64
+	etag string
+}
+
+func (s *staticServer) serve(ctx *Context) {
+	fileSystem, conf := s.fileSystem, s.conf
+	name := path.Clean("/" + ctx.Param("filepath"))
+
+	f, err := fileSystem.Open(name)
+	if err != nil {
+		if conf.SPA && os.IsNotExist(err) {
+			s.serveFile(ctx, "/"+conf.Index)
+			return
+		}
+		staticNotFound(ctx)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		staticNotFound(ctx)
+		return
+	}
+	if !stat.IsDir() {
+		s.serveContent(ctx, name, f, stat)
+		return
+	}
+
+	// 目录地址以 / 结尾，保证首页中的相对地址正确
+	if !strings.HasSuffix(ctx.R.URL.Path, "/") {
+		target := path.Base(ctx.R.URL.Path) + "/"
+		if ctx.R.URL.RawQuery != "" {
+			target += "?" + ctx.R.URL.RawQuery
+		}
+		http.Redirect(ctx.W, ctx.R, target, http.StatusMovedPermanently)
+		return
+	}
+	indexName := path.Join(name, conf.Index)
+	if index, err := fileSystem.Open(indexName); err == nil {
+		defer index.Close()
+		if indexStat, err := index.Stat(); err == nil && !indexStat.IsDir() {
+			s.serveContent(ctx, indexName, index, indexStat)
+			return
+		}
+	}
+	if conf.Browse {
+		dirList(ctx, f)
+		return
+	}
+	staticNotFound(ctx)
+}
+
+func (s *staticServer) serveFile(ctx *Context, name string) {
+	f, err := s.fileSystem.Open(name)
+	if err != nil {
+		staticNotFound(ctx)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		staticNotFound(ctx)
+		return
+	}
+	s.serveContent(ctx, name, f, stat)
+}
+
+// serveContent 返回文件内容，由 http.ServeContent 处理 Last-Modified、ETag 及 Range
+func (s *staticServer) serveContent(ctx *Context, name string, f http.File, stat os.FileInfo) {
+	if ctx.W.Header().Get("ETag") == "" {
+		if etag := s.etag(name, f, stat); etag != "" {
+			ctx.W.Header().Set("ETag", etag)
+		}
+	}
+	http.ServeContent(ctx.W, ctx.R, stat.Name(), stat.ModTime(), f)
+}
+
+// etag 按修改时间、大小生成弱 ETag
+// embed.FS 等文件系统中文件的修改时间为零，相同大小的文件会得到相同的 ETag，
+// 此时按文件内容计算 ETag 并缓存，计算失败时不设置 ETag
+func (s *staticServer) etag(name string, f http.File, stat os.FileInfo) string {
+	if !stat.ModTime().IsZero() {
+		return fmt.Sprintf(`W/"%x-%x"`, stat.ModTime().UnixNano(), stat.Size())
+	}
+	if v, ok := s.etags.Load(name); ok && v.(*contentETag).size == stat.Size() {
+		return v.(*contentETag).etag
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	etag := fmt.Sprintf(`"%x"`, h.Sum(nil)[:16])
+	s.etags.Store(name, &contentETag{size: stat.Size(), etag: etag})
+	return etag
+}
+
+// dirList 列出目录中的文件
+func dirList(ctx *Context, f http.File) {
+	files, err := f.Readdir(-1)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Error reading directory")
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+
+	ctx.W.Header().Set("Content-Type", "text/html; charset=utf-8")
+	ctx.W.WriteHeader(http.StatusOK)
+	fmt.Fprintf(ctx.W, "<pre>\n")
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			name += "/"
+		}
+		link := url.URL{Path: name}
+		fmt.Fprintf(ctx.W, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(name))
+	}
+	fmt.Fprintf(ctx.W, "</pre>\n")
+}
+
+// staticNotFound 文件不存在时使用 engine 的 NoRoute 处理函数，全局中间件已经执行过，只执行处理函数链中的最后一个
+func staticNotFound(ctx *Context) {
+	handlers := ctx.e.noRouteHandlers.load()
+	handlers[len(handlers)-1](ctx)
+}
@@ -0,0 +1,113 @@
+package msgo
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRouterGroup_Static(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "index.html"), []byte("<h1>index</h1>"), 0644)
+	os.MkdirAll(filepath.Join(root, "css"), 0755)
+	os.WriteFile(filepath.Join(root, "css", "index.css"), []byte("body{color:red}"), 0644)
+	os.MkdirAll(filepath.Join(root, "docs"), 0755)
+
+	engine := New()
+	engine.Group("/").Static("assets", root)
+	engine.Group("/").StaticFSWithConfig("app", http.Dir(root), StaticConfig{SPA: true})
+	engine.Group("/").StaticFSWithConfig("browse", http.Dir(root), StaticConfig{Browse: true})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/assets/css/index.css", http.StatusOK, "body{color:red}"},
+		{"/assets/", http.StatusOK, "<h1>index</h1>"},
+		{"/assets/none.css", http.StatusNotFound, ""},
+		{"/assets/docs/", http.StatusNotFound, ""},
+		{"/assets/docs", http.StatusMovedPermanently, ""},
+		{"/assets/../static_test.go", http.StatusNotFound, ""},
+		{"/app/user/list", http.StatusOK, "<h1>index</h1>"},
+		{"/browse/docs/", http.StatusOK, "<pre>\n</pre>\n"},
+	}
+	for _, tt := range tests {
+		w := performRequest(engine, http.MethodGet, tt.path)
+		if w.Code != tt.status || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("%s: status = %d, body = %q", tt.path, w.Code, w.Body.String())
+		}
+	}
+
+	w := performRequest(engine, http.MethodGet, "/assets/css/index.css")
+	etag, lastModified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q", etag, lastModified)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/assets/css/index.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/assets/css/index.css", nil)
+	req.Header.Set("Range", "bytes=0-3")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "body" {
+		t.Errorf("Range: status = %d, body = %q", w.Code, w.Body.String())
+	}
+}
+
+func TestEngine_FS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tpl/index.html":     {Data: []byte(`{{define "index.html"}}<a href="{{urlFor "index"}}">{{.}}</a>{{end}}`)},
+		"public/css/app.css": {Data: []byte("body{}")},
+		"public/a.txt":       {Data: []byte("aaa")},
+		"public/b.txt":       {Data: []byte("bbb")},
+	}
+
+	engine := New()
+	engine.LoadTemplateFS(fsys, "tpl/*.html")
+	engine.Group("/").Get("index", func(ctx *Context) {
+		ctx.HtmlTemplate(http.StatusOK, "index.html", "首页")
+	}).Named("index")
+	sub, _ := fs.Sub(fsys, "public")
+	engine.Group("/").StaticFromFS("assets", sub)
+
+	if w := performRequest(engine, http.MethodGet, "/index"); w.Body.String() != `<a href="/index">首页</a>` {
+		t.Errorf("/index: body = %q", w.Body.String())
+	}
+	if w := performRequest(engine, http.MethodGet, "/assets/css/app.css"); w.Code != http.StatusOK || w.Body.String() != "body{}" {
+		t.Errorf("/assets/css/app.css: status = %d, body = %q", w.Code, w.Body.String())
+	}
+
+	// 修改时间为零时按内容生成 ETag，相同大小的文件 ETag 不同
+	etagA := performRequest(engine, http.MethodGet, "/assets/a.txt").Header().Get("ETag")
+	etagB := performRequest(engine, http.MethodGet, "/assets/b.txt").Header().Get("ETag")
+	if etagA == "" || etagA == etagB {
+		t.Fatalf("ETag: a.txt = %q, b.txt = %q", etagA, etagB)
+	}
+	for _, tt := range []struct {
+		path, etag string
+		status     int
+	}{
+		{"/assets/b.txt", etagA, http.StatusOK},
+		{"/assets/a.txt", etagA, http.StatusNotModified},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("If-None-Match", tt.etag)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s If-None-Match %s: status = %d, want %d", tt.path, tt.etag, w.Code, tt.status)
+		}
+	}
+}
@@ -1,21 +1,50 @@
 package msgo
 
 import (
+	"fmt"
 	"strings"
 )
 
-// TreeNode / 为根节点
-// 以/user/login 为例
+type nodeType uint8
+
+const (
+	static   nodeType = iota // 静态路径
+	param                    // :id、{id} 或 * 匹配单级路径，可带约束
+	catchAll                 // ** 或 *name 匹配剩余的全部路径
+)
+
+// TreeNode 压缩前缀树(radix tree)
+// 以下列路由为例
+//
+//	/user/login
+//	/user/logout
+//	/user/get/:id
 //
-//	(第一级)
+// 生成的树为
 //
-// |---user (第二级)
-// |--------login (第三级)
+//	/user/ (静态节点)
+//	|---log (静态节点，公共前缀被压缩)
+//	|-------in
+//	|-------out
+//	|---get/
+//	|-------:id (参数节点)
+//
+// 匹配优先级: 静态节点 > 带约束的参数节点 > 参数节点 > 通配节点，与注册顺序无关
+// 参数值不满足约束时继续匹配其他路由
+// 树只在注册路由时修改，匹配过程中不修改任何节点
 type TreeNode struct {
-	name           string
-	child          []*TreeNode
-	routerFullPath string
+	name           string           // 静态节点为压缩后的路径片段; 参数节点为 :id 或 *; 通配节点为 ** 或 *name
+	nType          nodeType         // 节点类型
+	indices        string           // 静态子节点 name 的首字节，与 child 一一对应
+	child          []*TreeNode      // 静态子节点
+	paramChildren  []*TreeNode      // 参数子节点，带约束的在前
+	paramName      string           // 参数节点的参数名
+	constraint     *paramConstraint // 参数节点的约束
+	catchAllChild  *TreeNode        // 通配子节点
+	routerFullPath string           // 叶子节点对应的完整路由，注册时设置
 	leaf           bool
+	route          *route        // 叶子节点对应的路由
+	handlers       *handlerChain // 叶子节点所在请求方式的前缀树对应的路由处理函数链，ANY 路由每种请求方式不同
 }
 
 // Put path  example
@@ -24,85 +53,344 @@ type TreeNode struct {
 //	/user/logout
 //	/user/info
 //	/user/get/:id
-//	/user/del/:id
-//	/user/update/:id
+//	/user/del/:id<int>
+//	/user/update/{id:[0-9]+}
 //	/user/search
 //	/user/**
-func (root *TreeNode) Put(path string) {
+//
+// 同一位置的参数约束相同而参数名不同(如 /user/:id 与 /user/:name)，或通配符不在路由末尾时 panic
+// 插入路径上已有的子节点替换为复制的节点后再修改，原有的子节点不会被修改，
+// 因此 root.clone().Put(path) 得到新的树，原有的树可以继续被并发匹配
+func (root *TreeNode) Put(path string) *TreeNode {
+	fullPath := path
+	n := root.insertStatic(path[:nextWildcard(path)])
+	path = path[nextWildcard(path):]
+
+	for path != "" {
+		// 当前的动态路径段
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		seg := path[:end]
+		path = path[end:]
 
-	temp := root
+		if isCatchAll(seg) {
+			if path != "" {
+				panic(fmt.Sprintf("路由 %s 中的通配符 %s 只能放在路由的末尾", fullPath, seg))
+			}
+			n = n.insertCatchAll(seg, fullPath)
+			break
+		}
+		n = n.insertParam(seg, fullPath)
 
-	//if root.child == nil {
-	//	root.child = []&TreeNode{}
-	//}
+		// 动态路径段后的静态路径
+		i := nextWildcard(path)
+		n = n.insertChild(path[:i])
+		path = path[i:]
+	}
 
-	pathSub := strings.Split(path, "/")
-	for i, v := range pathSub {
+	n.leaf = true
+	n.routerFullPath = fullPath
+	return n
+}
 
-		if i == 0 {
-			continue
+// nextWildcard 返回下一个动态路径段(以 :、{ 或 * 开头)的起始位置，没有时返回 len(path)
+func nextWildcard(path string) int {
+	for i := 0; i < len(path); i++ {
+		if isWildcardStart(path, i) {
+			return i
 		}
+	}
+	return len(path)
+}
 
-		// 判断子节点中是否存在
-		exists := false
-		for _, tNode := range root.child {
-			if tNode.name == v {
-				exists = true
-				root = tNode
-				break
-			}
+func isWildcardStart(path string, i int) bool {
+	return (path[i] == ':' || path[i] == '*' || path[i] == '{') && (i == 0 || path[i-1] == '/')
+}
+
+// insertStatic 从静态节点 n 开始插入静态路径，返回路径结束处的节点
+func (n *TreeNode) insertStatic(path string) *TreeNode {
+	i := longestCommonPrefix(path, n.name)
+	if i < len(n.name) {
+		n.split(i)
+	}
+	return n.insertChild(path[i:])
+}
+
+// insertChild 在 n 的静态子节点中插入静态路径，返回路径结束处的节点
+func (n *TreeNode) insertChild(path string) *TreeNode {
+	if path == "" {
+		return n
+	}
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		n.child[i] = n.child[i].clone()
+		return n.child[i].insertStatic(path)
+	}
+	node := &TreeNode{name: path, nType: static}
+	n.indices += string(path[0])
+	n.child = append(n.child, node)
+	return node
+}
+
+// split 将静态节点 n 在 name 的 i 处拆分，n 保留公共前缀，原有内容移到新的子节点中
+func (n *TreeNode) split(i int) {
+	node := *n
+	node.name = n.name[i:]
+	*n = TreeNode{name: n.name[:i], nType: static, indices: node.name[:1], child: []*TreeNode{&node}}
+}
+
+func (n *TreeNode) insertParam(seg, fullPath string) *TreeNode {
+	name, expr := parseParamSegment(seg)
+	for i, child := range n.paramChildren {
+		childExpr := ""
+		if child.constraint != nil {
+			childExpr = child.constraint.expr
+		}
+		if childExpr != expr {
+			continue
+		}
+		if child.paramName != name {
+			panic(fmt.Sprintf("路由冲突: %s 中的 %s 与已注册的 %s 冲突", fullPath, seg, child.name))
 		}
+		n.paramChildren[i] = child.clone()
+		return n.paramChildren[i]
+	}
 
-		// 当子节点中没有时添加
-		if !exists {
-			node := &TreeNode{v, make([]*TreeNode, 0), "", false}
-			if i == len(pathSub)-1 {
-				node.leaf = true
-			}
-			child := append(root.child, node)
-			root.child = child
-			root = node
+	node := &TreeNode{name: seg, nType: param, paramName: name}
+	if expr == "" {
+		n.paramChildren = append(n.paramChildren, node)
+		return node
+	}
+	node.constraint = newParamConstraint(expr)
+	// 带约束的参数节点放在不带约束的参数节点之前
+	i := len(n.paramChildren)
+	if i > 0 && n.paramChildren[i-1].constraint == nil {
+		i--
+	}
+	n.paramChildren = append(n.paramChildren[:i], append([]*TreeNode{node}, n.paramChildren[i:]...)...)
+	return node
+}
+
+func (n *TreeNode) insertCatchAll(seg, fullPath string) *TreeNode {
+	if n.catchAllChild == nil {
+		n.catchAllChild = &TreeNode{name: seg, nType: catchAll}
+	} else if n.catchAllChild.name != seg {
+		panic(fmt.Sprintf("路由冲突: %s 中的 %s 与已注册的 %s 冲突", fullPath, seg, n.catchAllChild.name))
+	} else {
+		n.catchAllChild = n.catchAllChild.clone()
+	}
+	return n.catchAllChild
+}
+
+// clone 复制节点，子节点列表单独复制，子节点本身共享
+func (n *TreeNode) clone() *TreeNode {
+	node := *n
+	node.child = append([]*TreeNode(nil), n.child...)
+	node.paramChildren = append([]*TreeNode(nil), n.paramChildren...)
+	return &node
+}
+
+// countParams 路由中路径参数的个数
+func countParams(path string) int {
+	n := 0
+	for i := 0; i < len(path); i++ {
+		if isWildcardStart(path, i) {
+			n++
 		}
 	}
+	return n
+}
 
-	root = temp
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
 }
 
 // Get  /user/login/
-func (t *TreeNode) Get(path string) *TreeNode {
-	temp := t
-	pathSub := strings.Split(path, "/")
-	rs := ""
-	for i, v := range pathSub {
-		if i == 0 {
-			continue
+// 返回匹配到的节点，及匹配过程中捕获的路径参数
+//
+//	/user/get/:id   -> id
+//	/user/get/:id<int> -> id (值必须为整数)
+//	/user/*name     -> name (匹配剩余的全部路径)
+//	/user/**        -> ** (匹配剩余的全部路径)
+func (t *TreeNode) Get(path string) (*TreeNode, Params) {
+	var params Params
+	node := t.getValue(path, &params)
+	if node == nil {
+		return nil, nil
+	}
+	return node, params
+}
+
+// getValue 从节点 n 开始匹配 path，捕获的参数追加到 params 中
+// 当前分支匹配失败时回退已捕获的参数，再按优先级尝试下一个分支
+func (n *TreeNode) getValue(path string, params *Params) *TreeNode {
+	switch n.nType {
+	case static:
+		if !strings.HasPrefix(path, n.name) {
+			return nil
 		}
+		path = path[len(n.name):]
+	case param:
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end == 0 {
+			return nil
+		}
+		if n.constraint != nil && !n.constraint.match(path[:end]) {
+			return nil
+		}
+		if n.paramName != "" {
+			*params = append(*params, Param{Key: n.paramName, Value: path[:end], constraint: n.constraint})
+		}
+		path = path[end:]
+	case catchAll:
+		*params = append(*params, Param{Key: catchAllKey(n.name), Value: path})
+		return n
+	}
 
-		exists := false
-		for _, node := range t.child {
-			if v == node.name || "*" == node.name || strings.Contains(node.name, ":") {
-				exists = true
-				rs += "/" + node.name
-				t = node
-				node.routerFullPath = rs
-				//log.Printf("routerFullPath:%s \n", node.routerFullPath)
-				if i == len(pathSub)-1 {
-					return node
-				}
-				break
+	if path == "" && n.leaf {
+		return n
+	}
+
+	saved := len(*params)
+	if path != "" {
+		if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+			if node := n.child[i].getValue(path, params); node != nil {
+				return node
+			}
+		}
+		for _, child := range n.paramChildren {
+			if node := child.getValue(path, params); node != nil {
+				return node
 			}
+			*params = (*params)[:saved]
 		}
+	}
+	if n.catchAllChild != nil {
+		return n.catchAllChild.getValue(path, params)
+	}
+	return nil
+}
+
+// findCaseInsensitive 忽略大小写匹配 path，返回按注册路由修正大小写后的路径
+// 参数值及通配符匹配的部分保持原样
+func (n *TreeNode) findCaseInsensitive(path string, buf []byte) ([]byte, bool) {
+	switch n.nType {
+	case static:
+		if len(path) < len(n.name) || !equalFoldASCII(path[:len(n.name)], n.name) {
+			return nil, false
+		}
+		buf = append(buf, n.name...)
+		path = path[len(n.name):]
+	case param:
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end == 0 || (n.constraint != nil && !n.constraint.match(path[:end])) {
+			return nil, false
+		}
+		buf = append(buf, path[:end]...)
+		path = path[end:]
+	case catchAll:
+		return append(buf, path...), true
+	}
 
-		if !exists {
-			for _, node1 := range t.child {
-				if node1.name == "**" {
-					node1.routerFullPath = rs + "/" + node1.name
-					//log.Printf("routerFullPath not exists:%s \n", node1.routerFullPath)
-					return node1
+	if path == "" && n.leaf {
+		return buf, true
+	}
+
+	if path != "" {
+		// 首字节忽略大小写后可能对应多个静态子节点
+		for i := 0; i < len(n.indices); i++ {
+			if toLowerASCII(n.indices[i]) == toLowerASCII(path[0]) {
+				if rs, ok := n.child[i].findCaseInsensitive(path, buf); ok {
+					return rs, true
 				}
 			}
 		}
+		for _, child := range n.paramChildren {
+			if rs, ok := child.findCaseInsensitive(path, buf); ok {
+				return rs, true
+			}
+		}
 	}
-	t = temp
-	return nil
+	if n.catchAllChild != nil {
+		return n.catchAllChild.findCaseInsensitive(path, buf)
+	}
+	return nil, false
+}
+
+func equalFoldASCII(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if toLowerASCII(a[i]) != toLowerASCII(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func toLowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// isCatchAll ** 或 *name 匹配剩余的全部路径
+func isCatchAll(name string) bool {
+	return len(name) > 1 && name[0] == '*'
+}
+
+// catchAllKey ** 的参数名为 **, *name 的参数名为 name
+func catchAllKey(name string) string {
+	if name == "**" {
+		return name
+	}
+	return name[1:]
+}
+
+// Param 路由中捕获的单个路径参数
+type Param struct {
+	Key        string
+	Value      string
+	constraint *paramConstraint // 参数的约束，用于获取解析后的值
+}
+
+// TypedValue 按约束解析后的参数值
+// <int> 为 int64，<uint> 为 uint64，<float> 为 float64，<bool> 为 bool，其他为 string
+func (p Param) TypedValue() any {
+	if p.constraint == nil {
+		return p.Value
+	}
+	return p.constraint.value(p.Value)
+}
+
+// Params 路由中捕获的路径参数，按在路由中出现的顺序排列
+type Params []Param
+
+// Get 按参数名获取参数值
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// ByName 按参数名获取参数值，不存在时返回空字符串
+func (ps Params) ByName(name string) string {
+	val, _ := ps.Get(name)
+	return val
 }
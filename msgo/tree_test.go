@@ -2,6 +2,8 @@ package msgo
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -17,7 +19,7 @@ func TestTreeNode_Put_Get(t *testing.T) {
 	//root.Put("/index")
 	root.Put("/user/*/get")
 
-	node := root.Get("/")
+	node, _ := root.Get("/")
 	fmt.Println(node)
 
 	//node = root.Get("/index")
@@ -32,6 +34,205 @@ func TestTreeNode_Put_Get(t *testing.T) {
 	node = root.Get("/user/search34")
 	fmt.Println(node)
 	*/
-	node = root.Get("/user/1/get")
+	node, _ = root.Get("/user/1/get")
 	fmt.Println(node)
 }
+
+func TestTreeNode_Get_Params(t *testing.T) {
+	root := TreeNode{name: "/"}
+	root.Put("/user/get/:id")
+	root.Put("/user/:uid/order/:oid")
+	root.Put("/assets/**")
+	root.Put("/files/*filepath")
+
+	tests := []struct {
+		path   string
+		params Params
+	}{
+		{"/user/get/10", Params{{Key: "id", Value: "10"}}},
+		{"/user/7/order/99", Params{{Key: "uid", Value: "7"}, {Key: "oid", Value: "99"}}},
+		{"/assets/css/index.css", Params{{Key: "**", Value: "css/index.css"}}},
+		{"/files/a/b.txt", Params{{Key: "filepath", Value: "a/b.txt"}}},
+	}
+	for _, tt := range tests {
+		node, params := root.Get(tt.path)
+		if node == nil {
+			t.Errorf("%s: node not found", tt.path)
+			continue
+		}
+		if fmt.Sprint(params) != fmt.Sprint(tt.params) {
+			t.Errorf("%s: params = %v, want %v", tt.path, params, tt.params)
+		}
+	}
+}
+
+func TestTreeNode_Get_Priority(t *testing.T) {
+	root := TreeNode{name: "/"}
+	// 注册顺序不影响匹配的优先级
+	root.Put("/user/**")
+	root.Put("/user/:id")
+	root.Put("/user/search")
+	root.Put("/user/:id/info")
+	root.Put("/user/search/:key")
+
+	tests := []struct {
+		path     string
+		fullPath string
+	}{
+		{"/user/search", "/user/search"},
+		{"/user/10", "/user/:id"},
+		{"/user/10/info", "/user/:id/info"},
+		{"/user/search/go", "/user/search/:key"},
+		// 静态分支匹配失败时回退到参数分支
+		{"/user/search/info", "/user/search/:key"},
+		{"/user/searching/info", "/user/:id/info"},
+		{"/user/10/order", "/user/**"},
+		{"/user/", "/user/**"},
+	}
+	for _, tt := range tests {
+		node, _ := root.Get(tt.path)
+		if node == nil {
+			t.Errorf("%s: node not found", tt.path)
+			continue
+		}
+		if node.routerFullPath != tt.fullPath {
+			t.Errorf("%s: matched %s, want %s", tt.path, node.routerFullPath, tt.fullPath)
+		}
+	}
+
+	if node, _ := root.Get("/order"); node != nil {
+		t.Errorf("/order: matched %s, want nil", node.routerFullPath)
+	}
+}
+
+func TestTreeNode_Put_Conflict(t *testing.T) {
+	tests := []struct {
+		exists string
+		path   string
+	}{
+		{"/user/:id", "/user/:name"},
+		{"/user/:id/info", "/user/:name"},
+		{"/user/*", "/user/:name"},
+		{"/user/**", "/user/*name"},
+		{"/user/**", "/user/**/info"},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if err := recover(); err == nil {
+					t.Errorf("%s after %s: want panic", tt.path, tt.exists)
+				}
+			}()
+			root := TreeNode{name: "/"}
+			root.Put(tt.exists)
+			root.Put(tt.path)
+		}()
+	}
+}
+
+func TestTreeNode_Get_Constraint(t *testing.T) {
+	root := TreeNode{}
+	root.Put("/user/:name")
+	root.Put("/user/:id<int>")
+	root.Put("/post/{slug:[a-z-]+}")
+	root.Put("/post/:id<uint>/comments")
+	root.Put("/file/:ok<bool>")
+
+	tests := []struct {
+		path     string
+		fullPath string
+		value    any
+	}{
+		{"/user/10", "/user/:id<int>", int64(10)},
+		{"/user/-3", "/user/:id<int>", int64(-3)},
+		{"/user/tom", "/user/:name", "tom"},
+		{"/post/hello-world", "/post/{slug:[a-z-]+}", "hello-world"},
+		{"/post/12/comments", "/post/:id<uint>/comments", uint64(12)},
+		{"/file/true", "/file/:ok<bool>", true},
+	}
+	for _, tt := range tests {
+		node, params := root.Get(tt.path)
+		if node == nil {
+			t.Errorf("%s: node not found", tt.path)
+			continue
+		}
+		if node.routerFullPath != tt.fullPath {
+			t.Errorf("%s: matched %s, want %s", tt.path, node.routerFullPath, tt.fullPath)
+		}
+		if v := params[0].TypedValue(); v != tt.value {
+			t.Errorf("%s: value = %#v, want %#v", tt.path, v, tt.value)
+		}
+	}
+
+	for _, path := range []string{"/post/Hello", "/post/-1/comments", "/file/yes"} {
+		if node, _ := root.Get(path); node != nil {
+			t.Errorf("%s: matched %s, want nil", path, node.routerFullPath)
+		}
+	}
+}
+
+// benchRoutes 生成 1200 个路由，静态、参数各占一部分
+func benchRoutes() []string {
+	routes := make([]string, 0, 1200)
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 4; j++ {
+			routes = append(routes,
+				fmt.Sprintf("/api/v%d/resource%d", j, i),
+				fmt.Sprintf("/api/v%d/resource%d/:id", j, i),
+				fmt.Sprintf("/api/v%d/resource%d/:id/items/:item", j, i),
+			)
+		}
+	}
+	return routes
+}
+
+func BenchmarkTreeNode_getValue(b *testing.B) {
+	root := &TreeNode{}
+	for _, r := range benchRoutes() {
+		root.Put(r)
+	}
+	params := make(Params, 0, 2)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		if root.getValue("/api/v3/resource99/123/items/456", &params) == nil {
+			b.Fatal("route not found")
+		}
+	}
+}
+
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func benchEngine(b *testing.B, path string) {
+	engine := New()
+	group := engine.Group("/api")
+	handler := func(ctx *Context) {}
+	for _, r := range benchRoutes() {
+		group.Get(r[len("/api"):], handler)
+		group.Post(r[len("/api"):], handler)
+	}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	w := &discardWriter{header: http.Header{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkEngine_Static(b *testing.B) {
+	benchEngine(b, "/api/v2/resource50")
+}
+
+func BenchmarkEngine_Param(b *testing.B) {
+	benchEngine(b, "/api/v3/resource99/123/items/456")
+}
@@ -0,0 +1,124 @@
+package msgo
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Named 为路由命名，用于 Engine.URLFor 及模板函数 urlFor 生成路由地址，路由已被删除或替换时 panic
+//
+//	group.Get("/get/:id", handler).Named("user.get")
+func (h *RouteHandle) Named(name string) *RouteHandle {
+	e := h.router.engine
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if h.route.removed {
+		panic("路由名称 " + name + " 对应的路由 " + h.route.method + " " + h.route.path + " 已被删除")
+	}
+	e.nameRoute(h.route, name)
+	return h
+}
+
+// nameRoute 为路由命名，挂载的子 engine 同时为上级 engine 中对应的路由命名，调用时持有 engine 的锁
+func (e *Engine) nameRoute(rt *route, name string) {
+	if _, ok := e.namedRoutes[name]; ok {
+		panic("路由名称 " + name + " 重复")
+	}
+	rt.name = name
+	e.namedRoutes[name] = rt
+	if rt.mounted != nil {
+		p := e.parent
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.nameRoute(rt.mounted, name)
+	}
+}
+
+// URLFor 按路由名称生成路由地址，params 为参数名、参数值交替的列表
+// 路由中没有的参数作为查询参数添加到地址后
+//
+//	/user/get/:id        URLFor("user.get", "id", 10)             -> /user/get/10
+//	/user/get/:id        URLFor("user.get", "id", 10, "tab", "a") -> /user/get/10?tab=a
+//	/assets/**           URLFor("assets", "**", "css/index.css")  -> /assets/css/index.css
+func (e *Engine) URLFor(name string, params ...any) (string, error) {
+	e.mu.RLock()
+	rt, ok := e.namedRoutes[name]
+	e.mu.RUnlock()
+	if !ok {
+		return "", errors.New("路由名称 " + name + " 不存在")
+	}
+	if len(params)%2 != 0 {
+		return "", errors.New("路由 " + name + " 的参数必须是参数名、参数值成对的")
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[fmt.Sprint(params[i])] = fmt.Sprint(params[i+1])
+	}
+
+	var sb strings.Builder
+	// 挂载到上级 engine 时带上挂载的路径
+	sb.WriteString(e.mountPath())
+	path := rt.path
+	for path != "" {
+		i := nextWildcard(path)
+		sb.WriteString(path[:i])
+		path = path[i:]
+		if path == "" {
+			break
+		}
+
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		seg := path[:end]
+		path = path[end:]
+
+		if isCatchAll(seg) {
+			val, ok := values[catchAllKey(seg)]
+			if !ok {
+				return "", fmt.Errorf("路由 %s 缺少参数 %s", name, catchAllKey(seg))
+			}
+			delete(values, catchAllKey(seg))
+			sb.WriteString(escapeCatchAll(val))
+			continue
+		}
+
+		key, expr := parseParamSegment(seg)
+		if key == "" {
+			return "", fmt.Errorf("路由 %s 中的 * 没有参数名，无法生成地址", name)
+		}
+		val, ok := values[key]
+		if !ok {
+			return "", fmt.Errorf("路由 %s 缺少参数 %s", name, key)
+		}
+		if expr != "" && !newParamConstraint(expr).match(val) {
+			return "", fmt.Errorf("路由 %s 的参数 %s=%s 不满足约束 %s", name, key, val, expr)
+		}
+		delete(values, key)
+		sb.WriteString(url.PathEscape(val))
+	}
+
+	// 剩余的参数作为查询参数
+	if len(values) > 0 {
+		query := url.Values{}
+		for key, val := range values {
+			query.Set(key, val)
+		}
+		sb.WriteString("?")
+		sb.WriteString(query.Encode())
+	}
+	return sb.String(), nil
+}
+
+// escapeCatchAll 转义通配符匹配的路径，保留其中的 /
+func escapeCatchAll(val string) string {
+	segs := strings.Split(val, "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segs, "/")
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"path"
 	"strings"
 	"unicode"
 	"unsafe"
@@ -33,3 +34,22 @@ func StringToBytes(s string) []byte {
 		}{s, len(s)},
 	))
 }
+
+// CleanPath 规范化 url 路径: 以 / 开头，合并多个 /，处理 . 和 ..，保留末尾的 /
+//
+//	user/info      -> /user/info
+//	/user//info/   -> /user/info/
+//	/user/../info  -> /info
+func CleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
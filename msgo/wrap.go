@@ -0,0 +1,43 @@
+package msgo
+
+import "net/http"
+
+// Handle 使用 http.Handler 处理路由，method 为 ANY 时处理全部请求方式
+//
+//	engine.Group("/debug").Handle(http.MethodGet, "/vars", expvar.Handler())
+func (rg *routerGroup) Handle(method, api string, handler http.Handler, midFn ...MiddlewareFun) *RouteHandle {
+	return rg.add(method, api, WrapH(handler), midFn...)
+}
+
+// WrapH 将 http.Handler 转换为 Handler
+func WrapH(h http.Handler) Handler {
+	return func(ctx *Context) {
+		h.ServeHTTP(ctx.W, ctx.R)
+	}
+}
+
+// WrapF 将 http.HandlerFunc 转换为 Handler
+func WrapF(f http.HandlerFunc) Handler {
+	return func(ctx *Context) {
+		f(ctx.W, ctx.R)
+	}
+}
+
+// WrapMiddleware 将标准库形式的中间件 func(http.Handler) http.Handler 转换为 MiddlewareFun
+// 中间件传给下一个处理函数的 http.ResponseWriter、*http.Request 设置到同一个 Context 上，
+// 中间件执行完后恢复为原来的值
+func WrapMiddleware(m func(http.Handler) http.Handler) MiddlewareFun {
+	return func(next Handler) Handler {
+		return func(ctx *Context) {
+			w, r := ctx.W, ctx.R
+			defer func() {
+				ctx.W, ctx.R = w, r
+			}()
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx.W, ctx.R = w, r
+				next(ctx)
+			})).ServeHTTP(w, r)
+		}
+	}
+}